@@ -0,0 +1,168 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"http_server/internal/auth"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ACCOUNTDELETIONGRACE is how long a deleted account can still be restored
+// by logging in before it and everything that references it is removed.
+const ACCOUNTDELETIONGRACE time.Duration = time.Hour * 24 * 30
+
+// deleteAccount schedules the caller's account for deletion and logs it out
+// everywhere.
+func (c *apiConfig) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Password string `json:"password"`
+	}
+	type responseStruct struct {
+		DeletionRequestedAt time.Time `json:"deletion_requested_at"`
+		DeleteAfter         time.Time `json:"delete_after"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	userID := callerID(r)
+	user, err := c.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if wait := c.loginRetryAfter(r, user.Email); wait > 0 {
+		tooManyRequests(w, wait)
+		return
+	}
+	if err := auth.CheckPasswordHash(req.Password, user.HashedPassword); err != nil {
+		c.loginFailed(r, user.Email, uuid.NullUUID{UUID: user.ID, Valid: true})
+		http.Error(w, "Password is incorrect", http.StatusUnauthorized)
+		return
+	}
+	err = c.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		user, err = q.RequestUserDeletion(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		return q.RevokeAllUserTokens(r.Context(), userID)
+	})
+	if err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error requesting deletion of %s: %s", userID, err)
+		return
+	}
+	log.Printf("User %s requested deletion of their account", userID)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(&responseStruct{
+		DeletionRequestedAt: user.DeletionRequestedAt.Time,
+		DeleteAfter:         user.DeletionRequestedAt.Time.Add(ACCOUNTDELETIONGRACE),
+	})
+}
+
+// purgeDeletedAccounts removes accounts whose grace period is over every
+// interval until ctx is done. Their media rows are deleted in the same
+// transaction, the rest goes through ON DELETE CASCADE, and the uploaded
+// files are removed from the blob store once that has committed.
+func (c *apiConfig) purgeDeletedAccounts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cutoff := sql.NullTime{Time: time.Now().Add(-ACCOUNTDELETIONGRACE), Valid: true}
+		var files []database.DeletePurgeableMediaRow
+		var purged []uuid.UUID
+		err := c.inTx(ctx, func(q *database.Queries) error {
+			var err error
+			files, err = q.DeletePurgeableMedia(ctx, cutoff)
+			if err != nil {
+				return err
+			}
+			purged, err = q.PurgeDeletedUsers(ctx, cutoff)
+			return err
+		})
+		if err != nil {
+			log.Printf("Error purging deleted accounts: %s", err)
+		} else {
+			if len(purged) > 0 {
+				log.Printf("Purged %d deleted accounts", len(purged))
+			}
+			for _, f := range files {
+				c.deleteBlobs(ctx, f.BlobKey, f.ThumbnailKey)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// exportAccount streams a ZIP archive with everything we keep about the
+// caller: profile, chirps and sessions, one JSON file each.
+func (c *apiConfig) exportAccount(w http.ResponseWriter, r *http.Request) {
+	userID := callerID(r)
+	user, err := c.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	user.HashedPassword = ""
+	chirps, err := c.queries.ListUserChirps(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Could not export chirps", http.StatusInternalServerError)
+		log.Printf("Error listing chirps of %s: %s", userID, err)
+		return
+	}
+	sessions, err := c.queries.ListActiveSessions(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Could not export sessions", http.StatusInternalServerError)
+		log.Printf("Error listing sessions of %s: %s", userID, err)
+		return
+	}
+	if chirps == nil {
+		chirps = []database.Chirp{}
+	}
+	if sessions == nil {
+		sessions = []database.ListActiveSessionsRow{}
+	}
+
+	w.Header().Set(CONTENTTYPE, "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.zip"`)
+	w.WriteHeader(http.StatusOK)
+	archive := zip.NewWriter(w)
+	for _, file := range []struct {
+		name string
+		data any
+	}{
+		{"profile.json", user},
+		{"chirps.json", chirps},
+		{"sessions.json", sessions},
+	} {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			log.Printf("Error writing export of %s: %s", userID, err)
+			return
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			log.Printf("Error writing export of %s: %s", userID, err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("Error writing export of %s: %s", userID, err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"http_server/internal/auth"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// parseAdminEmails reads the comma separated ADMIN_EMAILS list.
+func parseAdminEmails(s string) map[string]bool {
+	emails := make(map[string]bool)
+	for _, email := range strings.Split(s, ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			emails[email] = true
+		}
+	}
+	return emails
+}
+
+// configuredAdmin reports whether ADMIN_EMAILS makes user an admin. The
+// email has to be verified, otherwise anyone could sign up with a listed
+// address first.
+func (c *apiConfig) configuredAdmin(user database.User) bool {
+	return c.adminEmails[strings.ToLower(user.Email)] && user.EmailVerifiedAt.Valid
+}
+
+// bootstrapAdmin grants the admin role to a user listed in ADMIN_EMAILS, so
+// a fresh deployment can get its first admin. The list stays the source of
+// truth: setUserRoles refuses to take the role away again.
+func (c *apiConfig) bootstrapAdmin(ctx context.Context, user database.User) database.User {
+	if !c.configuredAdmin(user) || slices.Contains(user.Roles, auth.RoleAdmin) {
+		return user
+	}
+	updated, err := c.queries.SetUserRoles(ctx, database.SetUserRolesParams{
+		ID:    user.ID,
+		Roles: append(slices.Clone(user.Roles), auth.RoleAdmin),
+	})
+	if err != nil {
+		log.Printf("Error granting admin role to %s: %s", user.ID, err)
+		return user
+	}
+	log.Printf("Granted admin role to %s from ADMIN_EMAILS", user.ID)
+	return updated
+}
+
+func (c *apiConfig) setUserRoles(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Roles []string `json:"roles"`
+	}
+	defer r.Body.Close()
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	for _, role := range req.Roles {
+		if !auth.IsRole(role) {
+			http.Error(w, "Unknown role "+role, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Roles == nil {
+		req.Roles = []string{}
+	}
+	user, err := c.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if c.configuredAdmin(user) && !slices.Contains(req.Roles, auth.RoleAdmin) {
+		http.Error(w, "User is an admin through ADMIN_EMAILS; remove them there first", http.StatusConflict)
+		return
+	}
+	user, err = c.queries.SetUserRoles(r.Context(), database.SetUserRolesParams{
+		ID:    userID,
+		Roles: req.Roles,
+	})
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		log.Printf("Error setting roles of user %s: %s", userID, err)
+		return
+	}
+	log.Printf("Admin %s set roles of user %s to %v", callerID(r), userID, user.Roles)
+	user.HashedPassword = ""
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&user)
+}
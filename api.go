@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"http_server/internal/auth"
 	"http_server/internal/database"
+	"http_server/internal/mail"
+	"http_server/internal/media"
+	"http_server/internal/moderation"
+	"http_server/internal/throttle"
 	"log"
 	"net/http"
-	"sort"
 	"sync/atomic"
 	"time"
 
@@ -17,14 +23,60 @@ import (
 const (
 	CONTENTTYPE string = "Content-Type"
 	APPTYPE     string = "application/json"
+
+	MAXCHIRPLENGTH int = 140
+
+	REFRESHTOKENTTL time.Duration = time.Hour * 1440 //60 days expire time, 24 hours * 60 days = 1440hours
+	// REFRESHGRACE is how long a rotated refresh token can still be used, so
+	// two tabs refreshing at once are not mistaken for token theft.
+	REFRESHGRACE time.Duration = time.Second * 30
+)
+
+// Errors from the rotation transaction of refreshToken.
+var (
+	// errRefreshRevoked means the token was revoked while the request was
+	// in flight.
+	errRefreshRevoked = errors.New("refresh token revoked")
+	// errRefreshReused means a token was replayed within the grace window
+	// after its replacement had been rotated too.
+	errRefreshReused = errors.New("refresh token reused")
 )
 
 type apiConfig struct {
 	fileServerHits atomic.Int32
+	db             *sql.DB
 	queries        database.Queries
 	Platform       string
-	secret         string
+	keys           *auth.Keyring
 	polka_key      string
+	mailer         mail.Mailer
+	appURL         string
+	hasher         auth.Hasher
+	passwordPolicy auth.PasswordPolicy
+	blobs          media.BlobStore
+	imageJobs      chan struct{}
+	moderator      *moderation.Moderator
+	emailLimiter   *throttle.Limiter
+	adminEmails    map[string]bool
+	ipLimiter      *throttle.Limiter
+	// Password reset mails have their own limits and a bounded queue.
+	resetEmailLimiter *throttle.Limiter
+	resetIPLimiter    *throttle.Limiter
+	passwordResets    chan string
+}
+
+// inTx runs fn with queries bound to a transaction, which is committed if fn
+// returns nil and rolled back otherwise.
+func (c *apiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(c.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -55,6 +107,7 @@ func (cfg *apiConfig) reset() http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		if cfg.Platform != "dev" {
 			http.Error(rw, "Forbidden", http.StatusForbidden)
+			return
 		}
 		cfg.fileServerHits.Swap(0)
 		cfg.queries.DropAllUsers(r.Context())
@@ -69,6 +122,7 @@ func (c *apiConfig) createUser() http.Handler {
 		type request struct {
 			Email    string `json:"email"`
 			Password string `json:"password"`
+			Handle   string `json:"handle"`
 		}
 		var req request
 
@@ -77,7 +131,18 @@ func (c *apiConfig) createUser() http.Handler {
 			http.Error(rw, "Can not decode request", http.StatusUnprocessableEntity)
 			return
 		}
-		hashedPassword, err := auth.HashPassword(req.Password)
+		if !c.checkPassword(rw, req.Password, req.Email) {
+			return
+		}
+		handle := generateHandle()
+		if req.Handle != "" {
+			var ok bool
+			if handle, ok = parseHandle(req.Handle); !ok {
+				http.Error(rw, "Handles are 3 to 30 letters, digits or underscores", http.StatusBadRequest)
+				return
+			}
+		}
+		hashedPassword, err := c.hasher.Hash(req.Password)
 		if err != nil {
 			http.Error(rw, "Error creating user password", http.StatusInternalServerError)
 			log.Printf("Error creating password hash: %s", err)
@@ -86,13 +151,21 @@ func (c *apiConfig) createUser() http.Handler {
 		params := database.CreateUserParams{
 			Email:          req.Email,
 			HashedPassword: hashedPassword,
+			Handle:         handle,
 		}
 		createdUser, err := c.queries.CreateUser(r.Context(), params)
+		if isUniqueViolation(err) {
+			http.Error(rw, "Email or handle is already taken", http.StatusConflict)
+			return
+		}
 		if err != nil {
 			http.Error(rw, "Interal database error", http.StatusInternalServerError)
 			log.Println(err)
 			return
 		}
+		if err := c.sendEmailVerification(r.Context(), createdUser, createdUser.Email); err != nil {
+			log.Printf("Error sending verification mail to %s: %s", createdUser.ID, err)
+		}
 		createdUser.HashedPassword = ""
 		rw.WriteHeader(http.StatusCreated)
 		json.NewEncoder(rw).Encode(&createdUser)
@@ -107,15 +180,6 @@ func (c *apiConfig) login() http.Handler {
 			Email    string `json:"email"`
 			Password string `json:"password"`
 		}
-		type responseStruct struct {
-			ID           uuid.UUID `json:"id"`
-			CreatedAt    time.Time `json:"created_at"`
-			UpdatedAt    time.Time `json:"updated_at"`
-			Email        string    `json:"email"`
-			Token        string    `json:"token"`
-			Refreshtoken string    `json:"refresh_token"`
-			IsChripyRed  bool      `json:"is_chirpy_red"`
-		}
 
 		defer r.Body.Close()
 		var req requestStruct
@@ -125,85 +189,202 @@ func (c *apiConfig) login() http.Handler {
 			return
 		}
 
+		if wait := c.loginRetryAfter(r, req.Email); wait > 0 {
+			tooManyRequests(w, wait)
+			return
+		}
 		user, err := c.queries.GetUserFromEmail(r.Context(), req.Email)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.loginFailed(r, req.Email, uuid.NullUUID{})
+			http.Error(w, "Authentication failed", http.StatusUnauthorized)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Could not retrieve user", http.StatusInternalServerError)
 			log.Printf("Error retrieving user from database: %s", err)
 			return
 		}
 		if err := auth.CheckPasswordHash(req.Password, user.HashedPassword); err != nil {
+			c.loginFailed(r, req.Email, uuid.NullUUID{UUID: user.ID, Valid: true})
 			http.Error(w, "Authentication failed", http.StatusUnauthorized)
 			return
 		}
-		res := responseStruct{
-			ID:           user.ID,
-			CreatedAt:    user.CreatedAt,
-			UpdatedAt:    user.UpdatedAt,
-			Email:        user.Email,
-			Token:        "",
-			Refreshtoken: "",
-			IsChripyRed:  user.IsChirpyRed,
+		if c.hasher.NeedsRehash(user.HashedPassword) {
+			c.rehashPassword(r, user, req.Password)
 		}
-
-		token, err := auth.MakeJWT(res.ID, c.secret, time.Second*3600)
-		if err != nil {
-			http.Error(w, "unable to create JWT token", http.StatusInternalServerError)
-			log.Printf("Unable to create JWT token: %s", err)
+		if s := standingOf(user); suspended(s) {
+			accountSuspended(w, s)
 			return
 		}
-		refreshToken, err := auth.MakeRefreshToken()
-		if err != nil {
-			http.Error(w, "Error creating refreshtoken", http.StatusInternalServerError)
+		if user.TotpEnabledAt.Valid {
+			c.startLoginChallenge(w, r, user)
 			return
 		}
-		_, err = c.queries.InsertRefreshToken(r.Context(), database.InsertRefreshTokenParams{
-			UserID:    res.ID,
-			Token:     refreshToken,
-			ExpiresAt: time.Now().Add(time.Hour * 1440)}) //60 days expire time, 24 hours * 60 days = 1440hours
+		c.startSession(w, r, user)
+	})
+}
 
-		if err != nil {
-			http.Error(w, "Error inserting token to database", http.StatusInternalServerError)
-		}
-		res.Refreshtoken = refreshToken
-		res.Token = token
-		if err := json.NewEncoder(w).Encode(&res); err != nil {
-			http.Error(w, "Error encoding response", http.StatusInternalServerError)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
+// rehashPassword replaces a hash made with outdated parameters after the
+// password was verified against user.HashedPassword. It only applies while
+// that hash is still current, so a password changed in the meantime is not
+// overwritten. Failures only mean the upgrade is retried next time.
+func (c *apiConfig) rehashPassword(r *http.Request, user database.User, password string) {
+	hashedPassword, err := c.hasher.Hash(password)
+	if err != nil {
+		log.Printf("Error rehashing password for %s: %s", user.ID, err)
+		return
+	}
+	err = c.queries.RehashUserPassword(r.Context(), database.RehashUserPasswordParams{
+		ID:             user.ID,
+		HashedPassword: hashedPassword,
+		OldHash:        user.HashedPassword,
+	})
+	if err != nil {
+		log.Printf("Error rehashing password for %s: %s", user.ID, err)
+	}
+}
 
+// checkPassword writes a 400 listing every policy violation and returns false
+// if password may not be used for the account with the given email.
+func (c *apiConfig) checkPassword(w http.ResponseWriter, password, email string) bool {
+	type responseStruct struct {
+		Error      string                 `json:"error"`
+		Violations []auth.PolicyViolation `json:"violations"`
+	}
+	err := c.passwordPolicy.Validate(password, email)
+	if err == nil {
+		return true
+	}
+	var pwErr *auth.PasswordError
+	if !errors.As(err, &pwErr) {
+		http.Error(w, "Could not check password", http.StatusInternalServerError)
+		log.Printf("Error checking password policy: %s", err)
+		return false
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(&responseStruct{
+		Error:      "Password does not meet the password policy",
+		Violations: pwErr.Violations,
 	})
+	return false
+}
+
+// startSession hands out an access token and a refresh token starting a new
+// token family to a user that has been fully authenticated.
+func (c *apiConfig) startSession(w http.ResponseWriter, r *http.Request, user database.User) {
+	type responseStruct struct {
+		ID           uuid.UUID `json:"id"`
+		CreatedAt    time.Time `json:"created_at"`
+		UpdatedAt    time.Time `json:"updated_at"`
+		Email        string    `json:"email"`
+		Token        string    `json:"token"`
+		Refreshtoken string    `json:"refresh_token"`
+		IsChripyRed  bool      `json:"is_chirpy_red"`
+	}
+	// Only a fully authenticated login clears the throttle; a correct
+	// password alone must not reset the count of wrong TOTP codes.
+	c.loginSucceeded(r, user.Email)
+	if user.DeletionRequestedAt.Valid {
+		// Logging in during the grace period restores the account, but
+		// only once the second factor has been passed too.
+		if err := c.queries.CancelUserDeletion(r.Context(), user.ID); err != nil {
+			log.Printf("Error cancelling deletion of %s: %s", user.ID, err)
+		}
+	}
+	user = c.bootstrapAdmin(r.Context(), user)
+	res := responseStruct{
+		ID:           user.ID,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+		Email:        user.Email,
+		Token:        "",
+		Refreshtoken: "",
+		IsChripyRed:  user.IsChirpyRed,
+	}
+
+	token, err := auth.MakeJWT(res.ID, c.keys, time.Second*3600, user.Roles...)
+	if err != nil {
+		http.Error(w, "unable to create JWT token", http.StatusInternalServerError)
+		log.Printf("Unable to create JWT token: %s", err)
+		return
+	}
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		http.Error(w, "Error creating refreshtoken", http.StatusInternalServerError)
+		return
+	}
+	_, err = c.queries.InsertRefreshToken(r.Context(), database.InsertRefreshTokenParams{
+		UserID:    res.ID,
+		Token:     refreshToken,
+		ExpiresAt: time.Now().Add(REFRESHTOKENTTL),
+		FamilyID:  uuid.New(),
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r)})
+
+	if err != nil {
+		http.Error(w, "Error inserting token to database", http.StatusInternalServerError)
+		return
+	}
+	res.Refreshtoken = refreshToken
+	res.Token = token
+	if err := json.NewEncoder(w).Encode(&res); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
 }
 
 func (c *apiConfig) getAllChirps(w http.ResponseWriter, r *http.Request) {
-	userID, err := uuid.Parse(r.URL.Query().Get("author_id"))
-	var data []database.Chirp
+	type responseStruct struct {
+		Chirps []chirpResponse `json:"chirps"`
+		page
+	}
+	pageReq, err := parsePageRequest(r.URL.Query())
 	if err != nil {
-		data, err = c.queries.GetAllChirps(r.Context())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := database.ListChirpsAscParams{ViewerID: c.viewerID(r), RowLimit: pageReq.Limit + 1}
+	if author := r.URL.Query().Get("author_id"); author != "" {
+		userID, err := uuid.Parse(author)
 		if err != nil {
-			http.Error(w, "Can not retrieve data", http.StatusNotFound)
+			http.Error(w, "Invalid author_id", http.StatusBadRequest)
 			return
 		}
+		params.AuthorID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+	if pageReq.Cursor != nil {
+		params.CursorCreatedAt = sql.NullTime{Time: pageReq.Cursor.CreatedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: pageReq.Cursor.ID, Valid: true}
+	}
+
+	var data []database.Chirp
+	if pageReq.queryDesc() {
+		data, err = c.queries.ListChirpsDesc(r.Context(), database.ListChirpsDescParams(params))
 	} else {
-		data, err = c.queries.GetAllChirpsByAuthor(r.Context(), userID)
-		if err != nil {
-			http.Error(w, "Could not retrieve data", http.StatusNotFound)
-			return
-		}
+		data, err = c.queries.ListChirpsAsc(r.Context(), params)
 	}
-	sorting := r.URL.Query().Get("sort")
-	if sorting == "desc" {
-		sort.Slice(data, func(i, j int) bool {
-			return data[i].CreatedAt.After(data[j].CreatedAt)
-		})
+	if err != nil {
+		http.Error(w, "Could not retrieve data", http.StatusInternalServerError)
+		log.Printf("Error listing chirps: %s", err)
+		return
 	}
-	if err := json.NewEncoder(w).Encode(&data); err != nil {
-		http.Error(w, "Could not marshal data", http.StatusInternalServerError)
+	data, p := paginate(data, pageReq, func(chirp database.Chirp) cursor {
+		return cursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+	})
+	chirps, err := c.chirpResponses(r.Context(), data, params.ViewerID)
+	if err != nil {
+		http.Error(w, "Could not retrieve data", http.StatusInternalServerError)
+		log.Printf("Error loading chirp engagement: %s", err)
 		return
 	}
 
+	setLinkHeader(w, r, p)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
 	w.WriteHeader(http.StatusOK)
-
+	if err := json.NewEncoder(w).Encode(&responseStruct{Chirps: chirps, page: p}); err != nil {
+		log.Printf("Could not marshal data: %s", err)
+	}
 }
 
 func (c *apiConfig) getSingleChirp() http.Handler {
@@ -213,12 +394,23 @@ func (c *apiConfig) getSingleChirp() http.Handler {
 			http.Error(rw, "Invalid UUID", http.StatusUnprocessableEntity)
 			return
 		}
+		viewer := c.viewerID(r)
 		data, err := c.queries.GetSingleChirp(r.Context(), id)
 		if err != nil {
 			http.Error(rw, err.Error(), http.StatusNotFound)
 			return
 		}
-		json.NewEncoder(rw).Encode(&data)
+		if !c.canSee(r.Context(), data, viewer) {
+			http.Error(rw, sql.ErrNoRows.Error(), http.StatusNotFound)
+			return
+		}
+		chirps, err := c.chirpResponses(r.Context(), []database.Chirp{data}, viewer)
+		if err != nil {
+			http.Error(rw, "Could not retrieve data", http.StatusInternalServerError)
+			log.Printf("Error loading chirp engagement: %s", err)
+			return
+		}
+		json.NewEncoder(rw).Encode(&chirps[0])
 		rw.Header().Add(CONTENTTYPE, APPTYPE)
 		rw.WriteHeader(http.StatusOK)
 
@@ -228,85 +420,230 @@ func (c *apiConfig) getSingleChirp() http.Handler {
 func (c *apiConfig) postChirp() http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		type requestStruct struct {
-			Body    string `json:"body"`
-			User_id string `json:"user_id"`
+			Body      string        `json:"body"`
+			User_id   string        `json:"user_id"`
+			InReplyTo uuid.NullUUID `json:"in_reply_to"`
+			MediaIDs  []uuid.UUID   `json:"media_ids"`
 		}
 		defer r.Body.Close()
-		token, err := auth.GetBearerToken(r.Header)
-		if err != nil {
-			http.Error(rw, "Could not extract token from headers. Did you send it?", http.StatusUnauthorized)
-			return
-		}
-		log.Printf("Have extracted token: %s", token)
-		userid, err := auth.ValidateJWT(token, c.secret)
-		if err != nil {
-			http.Error(rw, "Unable to verify token", http.StatusUnauthorized)
-			log.Printf("Unable to verify token, err: %s", err)
-			return
-		}
+		userid := callerID(r)
 		var req requestStruct
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(rw, "Error unmarshaling request data", http.StatusUnprocessableEntity)
 			return
 		}
-		if len(req.Body) > 140 {
+		if len(req.Body) > MAXCHIRPLENGTH {
 			http.Error(rw, `{"error": "Chirp is too long"}`, http.StatusBadRequest)
 			return
 		}
 
-		req.Body = cleanupInput(req.Body)
+		if req.InReplyTo.Valid {
+			parent, err := c.queries.GetSingleChirp(r.Context(), req.InReplyTo.UUID)
+			if err != nil || !c.canSee(r.Context(), parent, uuid.NullUUID{UUID: userid, Valid: true}) {
+				http.Error(rw, "Can not find chirp to reply to", http.StatusNotFound)
+				return
+			}
+		}
+
+		if len(req.MediaIDs) > MAXCHIRPMEDIA {
+			http.Error(rw, fmt.Sprintf("A chirp can have at most %d images", MAXCHIRPMEDIA), http.StatusBadRequest)
+			return
+		}
+
+		verdict, ok := c.moderateChirp(rw, r, req.Body)
+		if !ok {
+			return
+		}
 		data := database.CreateChirpParams{
-			Body:   req.Body,
-			UserID: userid,
+			Body:             verdict.Text,
+			UserID:           userid,
+			InReplyTo:        req.InReplyTo,
+			ModerationStatus: chirpStatus(verdict.Action),
 		}
 
-		createdChirp, err := c.queries.CreateChirp(r.Context(), data)
+		// The chirp is only created if every image can be attached to it.
+		var createdChirp database.Chirp
+		err := c.inTx(r.Context(), func(q *database.Queries) error {
+			if len(req.MediaIDs) > 0 {
+				count, err := q.CountUnattachedMedia(r.Context(), database.CountUnattachedMediaParams{
+					MediaIds: req.MediaIDs,
+					UserID:   userid,
+				})
+				if err != nil {
+					return err
+				}
+				if count != int64(len(req.MediaIDs)) {
+					return errUnknownMedia
+				}
+			}
+			var err error
+			createdChirp, err = q.CreateChirp(r.Context(), data)
+			if err != nil || len(req.MediaIDs) == 0 {
+				return err
+			}
+			attached, err := q.AttachMedia(r.Context(), database.AttachMediaParams{
+				ChirpID:  uuid.NullUUID{UUID: createdChirp.ID, Valid: true},
+				MediaIds: req.MediaIDs,
+				UserID:   userid,
+			})
+			if err != nil {
+				return err
+			}
+			if attached != int64(len(req.MediaIDs)) {
+				// Another chirp claimed some of them since they were counted.
+				return errMediaTaken
+			}
+			return nil
+		})
+		if errors.Is(err, errUnknownMedia) {
+			http.Error(rw, "Unknown or already used media", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, errMediaTaken) {
+			http.Error(rw, "Media was attached to another chirp", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(rw, "Interal database error", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+		c.recordModerationHits(r.Context(), userid, uuid.NullUUID{UUID: createdChirp.ID, Valid: true}, verdict)
+		chirps, err := c.chirpResponses(r.Context(), []database.Chirp{createdChirp}, uuid.NullUUID{UUID: userid, Valid: true})
 		if err != nil {
 			http.Error(rw, "Interal database error", http.StatusInternalServerError)
 			log.Println(err)
 			return
 		}
-		rw.WriteHeader(http.StatusCreated)
-		json.NewEncoder(rw).Encode(&createdChirp)
 		rw.Header().Add(CONTENTTYPE, APPTYPE)
+		rw.WriteHeader(http.StatusCreated)
+		json.NewEncoder(rw).Encode(&chirps[0])
 
 	})
 }
 
+// refreshToken exchanges a refresh token for a new access token and a new
+// refresh token. Every token handed out this way belongs to the family started
+// at login. Presenting a token that was already rotated means it has been
+// copied, so the whole family is revoked.
 func (c *apiConfig) refreshToken(w http.ResponseWriter, r *http.Request) {
 	type responseStruct struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 	tokenString, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		http.Error(w, "Unable to extract token", http.StatusUnprocessableEntity)
 		return
 	}
-	log.Printf("Token extracted: %s\n", tokenString)
-	//TODO: should be refactored at later stage. Not needed to extract entire token object. I just need the UUID.
 	token, err := c.queries.GetOneRefreshToken(r.Context(), tokenString)
 	if err != nil {
 		http.Error(w, "Unable to find refresh token", http.StatusNotFound)
 		log.Printf("Error extracting token from psql: %s\n", err)
 		return
 	}
-	log.Printf("Token after sql extraction: %s\n", token.Token)
 
-	if time.Now().After(token.ExpiresAt) || token.RevokedAt.Valid {
+	// A token rotated moments ago is most likely a second tab refreshing at
+	// the same time, not a thief; it gets the token that replaced it.
+	grace := token.ReplacedBy.Valid && token.RevokedAt.Valid && time.Since(token.RevokedAt.Time) < REFRESHGRACE
+	if token.ReplacedBy.Valid && !grace {
+		c.revokeTokenFamily(r, token)
+		http.Error(w, "Token has expired or has been revoked", http.StatusUnauthorized)
+		return
+	}
+	if time.Now().After(token.ExpiresAt) || (token.RevokedAt.Valid && !grace) {
+		http.Error(w, "Token has expired or has been revoked", http.StatusUnauthorized)
+		return
+	}
+	if !c.checkStanding(w, r, token.UserID) {
+		return
+	}
+	newRefreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		http.Error(w, "Error creating refreshtoken", http.StatusInternalServerError)
+		return
+	}
+	err = c.inTx(r.Context(), func(q *database.Queries) error {
+		if !grace {
+			rotated, err := q.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+				Token:      token.Token,
+				ReplacedBy: sql.NullString{String: newRefreshToken, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			if rotated > 0 {
+				_, err = q.InsertRefreshToken(r.Context(), database.InsertRefreshTokenParams{
+					Token:     newRefreshToken,
+					UserID:    token.UserID,
+					ExpiresAt: time.Now().Add(REFRESHTOKENTTL),
+					FamilyID:  token.FamilyID,
+					UserAgent: r.UserAgent(),
+					IpAddress: clientIP(r),
+				})
+				return err
+			}
+			// Another request rotated or revoked the token since we read
+			// it. A concurrent rotation is the same case as the grace window.
+			token, err = q.GetOneRefreshToken(r.Context(), token.Token)
+			if err != nil {
+				return err
+			}
+			if !token.ReplacedBy.Valid {
+				return errRefreshRevoked
+			}
+		}
+		// Every replay within the grace window gets the same replacement,
+		// so a rotated token can never mint more than one successor.
+		replacement, err := q.GetOneRefreshToken(r.Context(), token.ReplacedBy.String)
+		if err != nil {
+			return err
+		}
+		if replacement.ReplacedBy.Valid {
+			return errRefreshReused
+		}
+		if replacement.RevokedAt.Valid || time.Now().After(replacement.ExpiresAt) {
+			return errRefreshRevoked
+		}
+		newRefreshToken = replacement.Token
+		return nil
+	})
+	if errors.Is(err, errRefreshReused) {
+		c.revokeTokenFamily(r, token)
+	}
+	if errors.Is(err, errRefreshRevoked) || errors.Is(err, errRefreshReused) {
 		http.Error(w, "Token has expired or has been revoked", http.StatusUnauthorized)
 		return
 	}
-	authToken, err := auth.MakeJWT(token.UserID, c.secret, time.Duration(time.Hour))
+	if err != nil {
+		http.Error(w, "Could not rotate refresh token", http.StatusInternalServerError)
+		log.Printf("Error rotating refresh token: %s\n", err)
+		return
+	}
+	user, err := c.queries.GetUserByID(r.Context(), token.UserID)
+	if err != nil {
+		http.Error(w, "Could not retrieve user", http.StatusInternalServerError)
+		log.Printf("Error retrieving user %s: %s\n", token.UserID, err)
+		return
+	}
+	authToken, err := auth.MakeJWT(token.UserID, c.keys, time.Duration(time.Hour), user.Roles...)
 	if err != nil {
 		http.Error(w, "unable to create new token", http.StatusInternalServerError)
 		return
 	}
-	res := responseStruct{Token: authToken}
+	res := responseStruct{Token: authToken, RefreshToken: newRefreshToken}
 	json.NewEncoder(w).Encode(&res)
 	w.WriteHeader(http.StatusOK)
 
 }
 
+func (c *apiConfig) revokeTokenFamily(r *http.Request, token database.RefreshToken) {
+	log.Printf("Refresh token reuse detected for user %s, revoking token family %s\n", token.UserID, token.FamilyID)
+	if err := c.queries.RevokeTokenFamily(r.Context(), token.FamilyID); err != nil {
+		log.Printf("Error revoking token family %s: %s\n", token.FamilyID, err)
+	}
+}
+
 func (c *apiConfig) revokeRefreshToken(w http.ResponseWriter, r *http.Request) {
 	tokenString, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -333,40 +670,57 @@ func (c *apiConfig) revokeRefreshToken(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *apiConfig) updateUserEmailPassword(w http.ResponseWriter, r *http.Request) {
-	tokenString, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		http.Error(w, "Unable to extract JWT", http.StatusUnauthorized)
-		return
-	}
-	userID, err := auth.ValidateJWT(tokenString, c.secret)
+	userID := callerID(r)
 	type requestStruct struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email           string `json:"email"`
+		Password        string `json:"password"`
+		CurrentPassword string `json:"current_password"`
 	}
 
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "unable to process request", http.StatusUnprocessableEntity)
+		return
+	}
+	user, err := c.queries.GetUserByID(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Token not valid, GTFO", http.StatusUnauthorized)
+		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
-	var req requestStruct
-	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "unable to process request", http.StatusUnprocessableEntity)
+	if !c.checkCurrentPassword(w, r, user, req.CurrentPassword) {
+		return
+	}
+	if !c.checkPassword(w, req.Password, req.Email) {
 		return
 	}
-	req.Password, err = auth.HashPassword(req.Password)
+	hashedPassword, err := c.hasher.Hash(req.Password)
 	if err != nil {
 		http.Error(w, "Could not process password", http.StatusInternalServerError)
 		return
 	}
-	dbUser, err := c.queries.UpdateUserEmailPassword(r.Context(), database.UpdateUserEmailPasswordParams{
+	err = c.queries.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
 		ID:             userID,
-		Email:          req.Email,
-		HashedPassword: req.Password,
+		HashedPassword: hashedPassword,
 	})
 	if err != nil {
 		http.Error(w, "Unable to update database", http.StatusInternalServerError)
 		return
 	}
+	// A new address is only stored as pending until verifyEmail confirms it.
+	dbUser := user
+	if req.Email != user.Email {
+		dbUser, err = c.queries.SetPendingEmail(r.Context(), database.SetPendingEmailParams{
+			ID:           userID,
+			PendingEmail: sql.NullString{String: req.Email, Valid: true},
+		})
+		if err != nil {
+			http.Error(w, "Unable to update database", http.StatusInternalServerError)
+			return
+		}
+		if err := c.sendEmailVerification(r.Context(), dbUser, req.Email); err != nil {
+			log.Printf("Error sending verification mail to %s: %s", userID, err)
+		}
+	}
 	dbUser.HashedPassword = ""
 	json.NewEncoder(w).Encode(&dbUser)
 	w.WriteHeader(http.StatusOK)
@@ -375,22 +729,13 @@ func (c *apiConfig) updateUserEmailPassword(w http.ResponseWriter, r *http.Reque
 
 func (c *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 	/*
-		Authenticate user
+		User is authenticated by authorize()
 		Make sure user "owns" the chirp. Then delete chirp
 		if successfully deleted. return 204, else return 404
 		id, err := uuid.Parse(r.PathValue("chirpID"))
 	*/
 
-	tokenString, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		http.Error(w, "Unable to extract token", http.StatusUnauthorized)
-		return
-	}
-	userID, err := auth.ValidateJWT(tokenString, c.secret)
-	if err != nil {
-		http.Error(w, "Token not valid", http.StatusUnauthorized)
-		return
-	}
+	userID := callerID(r)
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
 		http.Error(w, "unable to parse chirpID", http.StatusNotFound)
@@ -405,10 +750,16 @@ func (c *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Not authorized to delete this chirp", http.StatusForbidden)
 		return
 	}
-	err = c.queries.DeleteSingleChirp(r.Context(), chirp.ID)
+	var blobs []string
+	err = c.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		blobs, err = deleteChirpWithMedia(r.Context(), q, chirp.ID)
+		return err
+	})
 	if err != nil {
 		http.Error(w, "Chirp not found", http.StatusNotFound)
 		return
 	}
+	c.deleteBlobs(r.Context(), blobs...)
 	w.WriteHeader(http.StatusNoContent)
 }
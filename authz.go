@@ -0,0 +1,53 @@
+package main
+
+import (
+	"http_server/internal/auth"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// authorize only lets a request through if it carries a valid access token
+// granting every one of scopes, issued to a user who is not suspended. The
+// token's claims are stored in the request context for the handler, see
+// callerID.
+func (c *apiConfig) authorize(next http.Handler, scopes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			http.Error(w, "Unable to extract token", http.StatusUnauthorized)
+			return
+		}
+		claims, err := auth.ParseJWT(tokenString, c.keys)
+		if err != nil {
+			http.Error(w, "Token not valid", http.StatusUnauthorized)
+			return
+		}
+		userID, err := claims.UserID()
+		if err != nil {
+			http.Error(w, "Token not valid", http.StatusUnauthorized)
+			return
+		}
+		if !c.checkStanding(w, r, userID) {
+			return
+		}
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				http.Error(w, "Token is missing scope "+scope, http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(auth.ContextWithClaims(r.Context(), claims)))
+	})
+}
+
+// callerID returns the authenticated user of a request that went through
+// authorize.
+func callerID(r *http.Request) uuid.UUID {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		return uuid.Nil
+	}
+	userID, _ := claims.UserID()
+	return userID
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// blockedBetween reports whether either user has blocked the other. Blocks
+// hide both accounts from each other, so the direction does not matter.
+func (c *apiConfig) blockedBetween(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	return c.queries.IsBlockedBetween(ctx, database.IsBlockedBetweenParams{
+		BlockerID: userID,
+		BlockedID: otherID,
+	})
+}
+
+// canSee reports whether viewer may see chirp: it must be visibleTo them and
+// neither of them may have blocked the other. Errors are logged and the chirp
+// treated as not visible.
+func (c *apiConfig) canSee(ctx context.Context, chirp database.Chirp, viewer uuid.NullUUID) bool {
+	if !visibleTo(chirp, viewer) {
+		return false
+	}
+	if !viewer.Valid || viewer.UUID == chirp.UserID {
+		return true
+	}
+	blocked, err := c.blockedBetween(ctx, viewer.UUID, chirp.UserID)
+	if err != nil {
+		log.Printf("Error checking blocks between %s and %s: %s", viewer.UUID, chirp.UserID, err)
+		return false
+	}
+	return !blocked
+}
+
+// blockUser hides the caller and the user from each other and ends any
+// follow between them.
+func (c *apiConfig) blockUser(w http.ResponseWriter, r *http.Request) {
+	userID := callerID(r)
+	blockedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	if blockedID == userID {
+		http.Error(w, "Can not block yourself", http.StatusBadRequest)
+		return
+	}
+	if _, err := c.queries.GetUserByID(r.Context(), blockedID); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	err = c.queries.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		http.Error(w, "Could not block user", http.StatusInternalServerError)
+		log.Printf("Error blocking user %s: %s", blockedID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unblockUser lifts a block. Follows it ended are not restored.
+func (c *apiConfig) unblockUser(w http.ResponseWriter, r *http.Request) {
+	userID := callerID(r)
+	blockedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	err = c.queries.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		http.Error(w, "Could not unblock user", http.StatusInternalServerError)
+		log.Printf("Error unblocking user %s: %s", blockedID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// muteUser keeps the user's chirps out of the caller's timeline and chirp
+// listings. Unlike a block the user is not told and can still interact.
+func (c *apiConfig) muteUser(w http.ResponseWriter, r *http.Request) {
+	userID := callerID(r)
+	mutedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	if mutedID == userID {
+		http.Error(w, "Can not mute yourself", http.StatusBadRequest)
+		return
+	}
+	if _, err := c.queries.GetUserByID(r.Context(), mutedID); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	err = c.queries.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		http.Error(w, "Could not mute user", http.StatusInternalServerError)
+		log.Printf("Error muting user %s: %s", mutedID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID := callerID(r)
+	mutedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	err = c.queries.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		http.Error(w, "Could not unmute user", http.StatusInternalServerError)
+		log.Printf("Error unmuting user %s: %s", mutedID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_server/internal/auth"
+	"http_server/internal/database"
+	"http_server/internal/mail"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const EMAILVERIFICATIONTTL time.Duration = time.Hour * 24
+
+// sendEmailVerification mails a link that confirms email belongs to user.
+// email is either the address the account was created with or a pending
+// new address.
+func (c *apiConfig) sendEmailVerification(ctx context.Context, user database.User, email string) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+	err = c.queries.InsertEmailVerification(ctx, database.InsertEmailVerificationParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(EMAILVERIFICATIONTTL),
+	})
+	if err != nil {
+		return err
+	}
+	link := c.appURL + "/api/verify-email?token=" + url.QueryEscape(token)
+	return c.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Confirm your Chirpy email address",
+		Body: fmt.Sprintf("Use this link within %s to confirm %s for your Chirpy account:\n%s\n\n"+
+			"If this was not you, you can ignore this message.\n", EMAILVERIFICATIONTTL, email, link),
+	})
+}
+
+// verifyEmail consumes a token from sendEmailVerification. A pending address
+// only becomes the login email of the account at this point.
+func (c *apiConfig) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Missing token", http.StatusBadRequest)
+		return
+	}
+	// The token is only used up if the address is confirmed, so a conflict
+	// can be resolved and the same link tried again.
+	var verification database.EmailVerification
+	var user database.User
+	consumed := false
+	err := c.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		verification, err = q.ConsumeEmailVerification(r.Context(), auth.HashToken(token))
+		if err != nil {
+			return err
+		}
+		consumed = true
+		user, err = q.ConfirmUserEmail(r.Context(), database.ConfirmUserEmailParams{
+			ID:    verification.UserID,
+			Email: verification.Email,
+		})
+		return err
+	})
+	if !consumed {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Email address is no longer pending", http.StatusGone)
+		return
+	}
+	if isUniqueViolation(err) {
+		http.Error(w, "Email address is already in use", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Interal database error", http.StatusInternalServerError)
+		log.Printf("Error confirming email for %s: %s", verification.UserID, err)
+		return
+	}
+	user.HashedPassword = ""
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&user)
+}
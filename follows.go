@@ -0,0 +1,65 @@
+package main
+
+import (
+	"http_server/internal/database"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func (c *apiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	userID := callerID(r)
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	if followeeID == userID {
+		http.Error(w, "Can not follow yourself", http.StatusBadRequest)
+		return
+	}
+	if _, err := c.queries.GetUserByID(r.Context(), followeeID); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	blocked, err := c.blockedBetween(r.Context(), userID, followeeID)
+	if err != nil {
+		http.Error(w, "Could not follow user", http.StatusInternalServerError)
+		log.Printf("Error checking blocks for %s: %s", followeeID, err)
+		return
+	}
+	if blocked {
+		http.Error(w, "Can not follow this user", http.StatusForbidden)
+		return
+	}
+	err = c.queries.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		http.Error(w, "Could not follow user", http.StatusInternalServerError)
+		log.Printf("Error following user %s: %s", followeeID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	userID := callerID(r)
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	err = c.queries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		http.Error(w, "Could not unfollow user", http.StatusInternalServerError)
+		log.Printf("Error unfollowing user %s: %s", followeeID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
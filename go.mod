@@ -8,4 +8,8 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.12.1
 	golang.org/x/crypto v0.49.0
+	golang.org/x/image v0.25.0
+	golang.org/x/text v0.40.0
 )
+
+require golang.org/x/sys v0.42.0 // indirect
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	RoleUser  string = "user"
+	RoleAdmin string = "admin"
+
+	ScopeChirpsWrite string = "chirps:write"
+	ScopeUsersWrite  string = "users:write"
+	ScopeAdmin       string = "admin"
+)
+
+// roleScopes lists the scopes an access token gets for each role of its user.
+var roleScopes = map[string][]string{
+	RoleUser:  {ScopeChirpsWrite, ScopeUsersWrite},
+	RoleAdmin: {ScopeAdmin},
+}
+
+// Claims are the claims of an access token.
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles  []string `json:"roles,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+func (c *Claims) UserID() (uuid.UUID, error) {
+	return uuid.Parse(c.Subject)
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// IsRole reports whether role is one of the roles known to the server.
+func IsRole(role string) bool {
+	_, ok := roleScopes[role]
+	return ok
+}
+
+// ScopesForRoles returns the union of the scopes granted by roles.
+func ScopesForRoles(roles []string) []string {
+	var scopes []string
+	for _, role := range roles {
+		for _, scope := range roleScopes[role] {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+type claimsKey struct{}
+
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
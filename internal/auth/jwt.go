@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"net/http"
@@ -12,33 +13,41 @@ import (
 	"github.com/google/uuid"
 )
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String()})
-	ss, err := token.SignedString([]byte(tokenSecret))
+func MakeJWT(userID uuid.UUID, keys *Keyring, expiresIn time.Duration, roles ...string) (string, error) {
+	ss, err := keys.sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String()},
+		Roles:  roles,
+		Scopes: ScopesForRoles(roles),
+	})
 	if err != nil {
 		return "", err
 	}
 	return ss, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
+func ParseJWT(tokenString string, keys *Keyring) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.keyfunc)
 	if err != nil {
-		return uuid.UUID{}, err
-	} else if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok {
-		id, err := uuid.Parse(claims.Subject)
-		if err != nil {
-			return uuid.UUID{}, err
+		return nil, err
+	} else if claims, ok := token.Claims.(*Claims); ok {
+		if _, err := claims.UserID(); err != nil {
+			return nil, err
 		}
-		return id, nil
+		return claims, nil
+	}
+	return nil, errors.New("unable to verify JWT")
+}
+
+func ValidateJWT(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	claims, err := ParseJWT(tokenString, keys)
+	if err != nil {
+		return uuid.UUID{}, err
 	}
-	return uuid.UUID{}, errors.New("unable to verify JWT")
+	return claims.UserID()
 }
 
 func MakeRefreshToken() (string, error) {
@@ -49,6 +58,13 @@ func MakeRefreshToken() (string, error) {
 
 }
 
+// HashToken hashes an opaque random token, such as a password reset token,
+// for storage so a database leak does not expose usable tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GetBearerToken(headers http.Header) (string, error) {
 	token := headers.Get("Authorization")
 	token, ok := strings.CutPrefix(token, "Bearer ")
@@ -11,7 +11,7 @@ import (
 
 func TestMakeJWT(t *testing.T) {
 	newuser := uuid.New()
-	token, err := MakeJWT(newuser, "myubersecret", time.Second*10)
+	token, err := MakeJWT(newuser, NewHMACKeyring("myubersecret"), time.Second*10)
 	if err != nil {
 		t.Fail()
 		t.Log(err)
@@ -23,11 +23,11 @@ func TestValidateJWT(t *testing.T) {
 	user1 := uuid.New()
 	user2 := uuid.New()
 
-	token1, _ := MakeJWT(user1, "myubersecret", time.Second*10)
-	token2, _ := MakeJWT(user2, "myubersecret", time.Millisecond*10)
+	token1, _ := MakeJWT(user1, NewHMACKeyring("myubersecret"), time.Second*10)
+	token2, _ := MakeJWT(user2, NewHMACKeyring("myubersecret"), time.Millisecond*10)
 	time.Sleep(time.Second)
 
-	user1uuid, err := ValidateJWT(token1, "myubersecret")
+	user1uuid, err := ValidateJWT(token1, NewHMACKeyring("myubersecret"))
 	if err != nil {
 		t.Logf("Failed to validate token: %s", err)
 		t.Fail()
@@ -37,7 +37,7 @@ func TestValidateJWT(t *testing.T) {
 
 	}
 
-	_, err = ValidateJWT(token2, "myubersecret")
+	_, err = ValidateJWT(token2, NewHMACKeyring("myubersecret"))
 	if err == nil {
 		t.Log("Got no error when expecting error")
 		t.Fail()
@@ -49,7 +49,7 @@ func TestValidateJWT(t *testing.T) {
 func TestGetBearerToken(t *testing.T) {
 	req := httptest.NewRequest("POST", "/", nil)
 	newuser := uuid.New()
-	token, _ := MakeJWT(newuser, "myubersecret", time.Second*300)
+	token, _ := MakeJWT(newuser, NewHMACKeyring("myubersecret"), time.Second*300)
 	req.Header.Add("Authorization", "Bearer "+token)
 	result, err := GetBearerToken(req.Header)
 	if err != nil {
@@ -63,3 +63,24 @@ func TestGetBearerToken(t *testing.T) {
 		t.Logf("Extracted %s, expected %s", result, token)
 	}
 }
+
+func TestParseJWTScopes(t *testing.T) {
+	keys := NewHMACKeyring("myubersecret")
+	user := uuid.New()
+	token, _ := MakeJWT(user, keys, time.Minute, RoleUser, RoleAdmin)
+	claims, err := ParseJWT(token, keys)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %s", err)
+	}
+	for _, scope := range []string{ScopeChirpsWrite, ScopeUsersWrite, ScopeAdmin} {
+		if !claims.HasScope(scope) {
+			t.Errorf("Expected scope %s in %v", scope, claims.Scopes)
+		}
+	}
+
+	token, _ = MakeJWT(user, keys, time.Minute, RoleUser)
+	claims, _ = ParseJWT(token, keys)
+	if claims.HasScope(ScopeAdmin) {
+		t.Error("Plain users must not get the admin scope")
+	}
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is one entry of a Keyring. Keys without a private part can only
+// verify tokens, which is how retired keys are kept around during rotation.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private any
+	public  any
+}
+
+// Keyring signs tokens with its active key and verifies tokens signed by any
+// of its keys, looked up by the "kid" header.
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	order  []string
+}
+
+// NewHMACKeyring returns a keyring with a single HS256 key. It exists for
+// development setups without key files and is never published in the JWKS.
+func NewHMACKeyring(secret string) *Keyring {
+	key := &SigningKey{ID: "hmac", Method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+	return &Keyring{active: key, keys: map[string]*SigningKey{key.ID: key}, order: []string{key.ID}}
+}
+
+// LoadKeyring reads PEM encoded keys from paths. The first file must hold a
+// private key and becomes the signing key. The remaining files can hold
+// private or public keys and are only used to verify tokens.
+func LoadKeyring(paths ...string) (*Keyring, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no key files given")
+	}
+	ring := &Keyring{keys: map[string]*SigningKey{}}
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParseSigningKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if i == 0 {
+			if key.private == nil {
+				return nil, fmt.Errorf("%s: signing key must be a private key", path)
+			}
+			ring.active = key
+		}
+		if _, ok := ring.keys[key.ID]; ok {
+			continue
+		}
+		ring.keys[key.ID] = key
+		ring.order = append(ring.order, key.ID)
+	}
+	return ring, nil
+}
+
+// ParseSigningKey parses a PKCS#8, PKCS#1 or PKIX PEM block holding an
+// Ed25519 or RSA key.
+func ParseSigningKey(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{}
+	switch k := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.public = jwt.SigningMethodEdDSA, k
+	case *rsa.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.public = jwt.SigningMethodRS256, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	key.ID, err = keyID(key.public)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// keyID derives a stable kid from the public key, so the same key file always
+// gets the same ID on every instance.
+func keyID(public any) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
+
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.private)
+}
+
+func (k *Keyring) keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS returns the public part of every asymmetric key in the keyring.
+func (k *Keyring) JWKS() []JWK {
+	jwks := []JWK{}
+	for _, id := range k.order {
+		key := k.keys[id]
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch pub := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType, jwk.Curve = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		default:
+			continue
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
+
+// KeyringFromEnv builds the keyring from a comma separated list of key files,
+// falling back to an HMAC secret when no files are configured.
+func KeyringFromEnv(keyFiles, secret string) (*Keyring, error) {
+	if strings.TrimSpace(keyFiles) == "" {
+		return NewHMACKeyring(secret), nil
+	}
+	var paths []string
+	for _, path := range strings.Split(keyFiles, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return LoadKeyring(paths...)
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func writeKey(t *testing.T, dir, name string, key any, public bool) string {
+	t.Helper()
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKeyringSignAndValidate(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	for name, path := range map[string]string{
+		"ed25519": writeKey(t, dir, "ed25519.pem", edKey, false),
+		"rsa":     writeKey(t, dir, "rsa.pem", rsaKey, false),
+	} {
+		keys, err := LoadKeyring(path)
+		if err != nil {
+			t.Fatalf("%s: could not load keyring: %s", name, err)
+		}
+		user := uuid.New()
+		token, err := MakeJWT(user, keys, time.Minute)
+		if err != nil {
+			t.Fatalf("%s: could not sign token: %s", name, err)
+		}
+		got, err := ValidateJWT(token, keys)
+		if err != nil {
+			t.Fatalf("%s: could not validate token: %s", name, err)
+		}
+		if got != user {
+			t.Errorf("%s: got user %s, want %s", name, got, user)
+		}
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldPub, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	oldPath := writeKey(t, dir, "old.pem", oldKey, false)
+	oldPubPath := writeKey(t, dir, "old.pub.pem", oldPub, true)
+	newPath := writeKey(t, dir, "new.pem", newKey, false)
+
+	oldRing, _ := LoadKeyring(oldPath)
+	user := uuid.New()
+	oldToken, _ := MakeJWT(user, oldRing, time.Minute)
+
+	rotated, err := LoadKeyring(newPath, oldPubPath)
+	if err != nil {
+		t.Fatalf("could not load rotated keyring: %s", err)
+	}
+	if _, err := ValidateJWT(oldToken, rotated); err != nil {
+		t.Errorf("token signed with the previous key should still validate: %s", err)
+	}
+	if len(rotated.JWKS()) != 2 {
+		t.Errorf("expected both keys in the JWKS, got %d", len(rotated.JWKS()))
+	}
+
+	newOnly, _ := LoadKeyring(newPath)
+	if _, err := ValidateJWT(oldToken, newOnly); err == nil {
+		t.Error("token signed with a dropped key should not validate")
+	}
+}
+
+func TestKeyringRejectsOtherAlgorithms(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	keys, _ := LoadKeyring(writeKey(t, dir, "ed25519.pem", edKey, false))
+
+	hmac := NewHMACKeyring("myubersecret")
+	hmac.active.ID = keys.active.ID
+	token, _ := MakeJWT(uuid.New(), hmac, time.Minute)
+	if _, err := ValidateJWT(token, keys); err == nil {
+		t.Error("HS256 token should not validate against an Ed25519 key")
+	}
+	if len(hmac.JWKS()) != 0 {
+		t.Error("HMAC keys must not be published")
+	}
+}
+
+func TestLoadKeyringNeedsPrivateSigningKey(t *testing.T) {
+	dir := t.TempDir()
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := LoadKeyring(writeKey(t, dir, "pub.pem", pub, true)); err == nil {
+		t.Error("expected an error when the signing key has no private part")
+	}
+}
@@ -1,13 +1,40 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 15)
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// Hasher creates password hashes. Hashes are self describing, so
+// CheckPasswordHash can verify them whichever Hasher created them.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether hash was made by another algorithm or with
+	// other parameters than the ones the Hasher uses now.
+	NeedsRehash(hash string) bool
+	// MaxPasswordLength is the longest password in bytes the algorithm
+	// takes into account.
+	MaxPasswordLength() int
+}
+
+// BcryptHasher stores hashes in the usual $2a$<cost>$ format.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
 	if err != nil {
 		log.Printf("Error occured during password hashing: %s", err.Error())
 		return "", err
@@ -15,9 +42,156 @@ func HashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+// MaxPasswordLength is 72, bcrypt ignores everything past it.
+func (h BcryptHasher) MaxPasswordLength() int {
+	return 72
+}
+
+// Argon2idHasher stores hashes in PHC string format:
+// $argon2id$v=19$m=<KiB>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2id uses the OWASP recommended minimum parameters.
+var DefaultArgon2id = Argon2idHasher{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+// MaxPasswordLength is not an argon2id limit, it only keeps absurdly long
+// inputs out.
+func (h Argon2idHasher) MaxPasswordLength() int {
+	return 1024
+}
+
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	return err != nil ||
+		params.Memory != h.Memory ||
+		params.Iterations != h.Iterations ||
+		params.Parallelism != h.Parallelism ||
+		uint32(len(salt)) != h.SaltLength ||
+		uint32(len(key)) != h.KeyLength
+}
+
+func parseArgon2id(hash string) (params Argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return params, nil, nil, errors.New("not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+	if salt, err = phcEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, err
+	}
+	if key, err = phcEncoding.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
+}
+
+// CheckPasswordHash verifies password against a bcrypt or argon2id hash.
 func CheckPasswordHash(password, hash string) error {
-	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
-		return err
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := parseArgon2id(hash)
+		if err != nil {
+			return err
+		}
+		got := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+// ParseHasher builds a Hasher from a spec such as "bcrypt", "bcrypt:cost=12",
+// "argon2id" or "argon2id:m=65536,t=3,p=4". An empty spec means argon2id with
+// DefaultArgon2id parameters.
+func ParseHasher(spec string) (Hasher, error) {
+	name, params, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	values := map[string]uint64{}
+	if params != "" {
+		for _, param := range strings.Split(params, ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid password hasher parameter %q", param)
+			}
+			values[k] = n
+		}
+	}
+	switch name {
+	case "bcrypt":
+		h := BcryptHasher{Cost: bcrypt.DefaultCost}
+		for k, v := range values {
+			if k != "cost" {
+				return nil, fmt.Errorf("unknown bcrypt parameter %q", k)
+			}
+			h.Cost = int(v)
+		}
+		if h.Cost < bcrypt.MinCost || h.Cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return h, nil
+	case "", "argon2id":
+		h := DefaultArgon2id
+		for k, v := range values {
+			switch k {
+			case "m":
+				h.Memory = uint32(v)
+			case "t":
+				h.Iterations = uint32(v)
+			case "p":
+				if v > 255 {
+					return nil, errors.New("argon2id parallelism must be at most 255")
+				}
+				h.Parallelism = uint8(v)
+			default:
+				return nil, fmt.Errorf("unknown argon2id parameter %q", k)
+			}
+		}
+		if h.Memory < 8*uint32(h.Parallelism) || h.Iterations < 1 || h.Parallelism < 1 {
+			return nil, errors.New("argon2id needs t >= 1, p >= 1 and m >= 8*p")
+		}
+		return h, nil
 	}
-	return nil
+	return nil, fmt.Errorf("unknown password hasher %q", name)
 }
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHashers(t *testing.T) {
+	hashers := map[string]Hasher{
+		"bcrypt":   BcryptHasher{Cost: 4},
+		"argon2id": Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	}
+	for name, h := range hashers {
+		hash, err := h.Hash("correct horse")
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if err := CheckPasswordHash("correct horse", hash); err != nil {
+			t.Errorf("%s: correct password rejected: %s", name, err)
+		}
+		if err := CheckPasswordHash("wrong horse", hash); !errors.Is(err, ErrPasswordMismatch) {
+			t.Errorf("%s: wrong password gave %v", name, err)
+		}
+		if h.NeedsRehash(hash) {
+			t.Errorf("%s: fresh hash should not need a rehash", name)
+		}
+		for other, o := range hashers {
+			if other != name && !o.NeedsRehash(hash) {
+				t.Errorf("%s hash should need a rehash for %s", name, other)
+			}
+		}
+	}
+}
+
+func TestNeedsRehashOnParameterChange(t *testing.T) {
+	hash, _ := BcryptHasher{Cost: 4}.Hash("pw")
+	if !(BcryptHasher{Cost: 5}).NeedsRehash(hash) {
+		t.Error("bcrypt cost change should need a rehash")
+	}
+	old := Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hash, _ = old.Hash("pw")
+	upgraded := old
+	upgraded.Iterations = 2
+	if !upgraded.NeedsRehash(hash) {
+		t.Error("argon2id parameter change should need a rehash")
+	}
+}
+
+func TestParseHasher(t *testing.T) {
+	h, err := ParseHasher("argon2id:m=65536,t=3,p=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := h.(Argon2idHasher); a.Memory != 65536 || a.Iterations != 3 || a.Parallelism != 4 {
+		t.Errorf("unexpected parameters %+v", a)
+	}
+	if h, _ := ParseHasher("bcrypt:cost=12"); h.(BcryptHasher).Cost != 12 {
+		t.Errorf("unexpected bcrypt hasher %+v", h)
+	}
+	if h, _ := ParseHasher(""); h != DefaultArgon2id {
+		t.Errorf("empty spec should give the default, got %+v", h)
+	}
+	for _, spec := range []string{"scrypt", "bcrypt:cost=40", "argon2id:x=1", "argon2id:t=0"} {
+		if _, err := ParseHasher(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Codes of the rules a password can break.
+const (
+	PasswordTooShort   = "too_short"
+	PasswordTooLong    = "too_long"
+	PasswordTooWeak    = "too_weak"
+	PasswordIsEmail    = "matches_email"
+	PasswordIsBreached = "breached"
+)
+
+type PolicyViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordError lists every rule a password breaks, so clients can show
+// them all at once.
+type PasswordError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PasswordError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return "password rejected: " + strings.Join(msgs, "; ")
+}
+
+type PasswordPolicy struct {
+	MinLength  int     // characters
+	MaxLength  int     // bytes, see Hasher.MaxPasswordLength; 0 for no limit
+	MinEntropy float64 // bits, see EstimateEntropy
+	Breached   *BreachedList
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:  8,
+	MaxLength:  DefaultArgon2id.MaxPasswordLength(),
+	MinEntropy: 40,
+}
+
+// PolicyFor returns DefaultPasswordPolicy with MaxLength taken from h.
+func PolicyFor(h Hasher) PasswordPolicy {
+	p := DefaultPasswordPolicy
+	p.MaxLength = h.MaxPasswordLength()
+	return p
+}
+
+// Validate returns a *PasswordError if password breaks any rule, nil
+// otherwise. email is the address of the account, if known.
+func (p PasswordPolicy) Validate(password, email string) error {
+	var violations []PolicyViolation
+	add := func(code, format string, args ...any) {
+		violations = append(violations, PolicyViolation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+	if utf8.RuneCountInString(password) < p.MinLength {
+		add(PasswordTooShort, "must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		add(PasswordTooLong, "must be at most %d bytes long", p.MaxLength)
+	}
+	if EstimateEntropy(password) < p.MinEntropy {
+		add(PasswordTooWeak, "is too easy to guess, use more and more varied characters")
+	}
+	if email != "" {
+		local, _, _ := strings.Cut(email, "@")
+		if strings.EqualFold(password, email) || strings.EqualFold(password, local) {
+			add(PasswordIsEmail, "must not be your email address")
+		}
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		add(PasswordIsBreached, "appears in a list of breached passwords")
+	}
+	if violations != nil {
+		return &PasswordError{Violations: violations}
+	}
+	return nil
+}
+
+// EstimateEntropy gives a rough strength estimate in bits: the size of the
+// character classes used, raised to the password length. Repeated
+// characters only count half, so "aaaaaaaaaa" does not look strong.
+func EstimateEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	seen := map[rune]bool{}
+	length := 0.0
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+		if seen[r] {
+			length += 0.5
+		} else {
+			length++
+			seen[r] = true
+		}
+	}
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return length * math.Log2(float64(pool))
+}
+
+// BreachedList holds SHA-1 hashes of known breached passwords, bucketed by
+// their first five hex digits like the k-anonymity range API of Have I Been
+// Pwned, so a remote range lookup can replace the local file later.
+type BreachedList struct {
+	buckets map[string][]string
+}
+
+// LoadBreachedList reads a file with one uppercase or lowercase SHA-1 hex
+// hash per line, optionally followed by ":<count>" as in the HIBP downloads.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	list := &BreachedList{buckets: map[string][]string{}}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if hash == "" {
+			continue
+		}
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("%s:%d: not a SHA-1 hash", path, line)
+		}
+		hash = strings.ToUpper(hash)
+		list.buckets[hash[:5]] = append(list.buckets[hash[:5]], hash[5:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, suffixes := range list.buckets {
+		slices.Sort(suffixes)
+	}
+	return list, nil
+}
+
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := slices.BinarySearch(l.buckets[hash[:5]], hash[5:])
+	return found
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func violationCodes(err error) []string {
+	var pwErr *PasswordError
+	if !errors.As(err, &pwErr) {
+		return nil
+	}
+	var codes []string
+	for _, v := range pwErr.Violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "breached.txt")
+	// SHA-1 of "correct horse battery staple", HIBP style with a count.
+	data := "ABF7AAD6438836DBE526AA231ABDE2D0EEF74D42:42\n\n" +
+		"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	breached, err := LoadBreachedList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := DefaultPasswordPolicy
+	policy.Breached = breached
+
+	cases := []struct {
+		password string
+		email    string
+		want     []string
+	}{
+		{"", "", []string{PasswordTooShort, PasswordTooWeak}},
+		{"aaaaaaaaaaaa", "", []string{PasswordTooWeak}},
+		{"Tr0ub4dor&3x", "", nil},
+		{"Tr0ub4dor&3x", "tr0ub4dor&3x@example.com", []string{PasswordIsEmail}},
+		{"correct horse battery staple", "", []string{PasswordIsBreached}},
+	}
+	for _, c := range cases {
+		got := violationCodes(policy.Validate(c.password, c.email))
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%q: got %v, want %v", c.password, got, c.want)
+		}
+	}
+}
+
+func TestPolicyForHasher(t *testing.T) {
+	password := strings.Repeat("Tr0ub4dor&3x", 8) // 96 bytes
+	if got := violationCodes(PolicyFor(DefaultArgon2id).Validate(password, "")); got != nil {
+		t.Errorf("argon2id: got %v, want no violations", got)
+	}
+	got := violationCodes(PolicyFor(BcryptHasher{Cost: 10}).Validate(password, ""))
+	if !slices.Equal(got, []string{PasswordTooLong}) {
+		t.Errorf("bcrypt: got %v, want %v", got, []string{PasswordTooLong})
+	}
+}
+
+func TestLoadBreachedListRejectsGarbage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	os.WriteFile(path, []byte("password\n"), 0600)
+	if _, err := LoadBreachedList(path); err == nil {
+		t.Error("expected an error for a line that is not a hash")
+	}
+}
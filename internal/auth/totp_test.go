@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 test secret, truncated to six digits.
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, c := range cases {
+		step, ok := ValidateTOTP(secret, c.code, time.Unix(c.unix, 0))
+		if !ok {
+			t.Errorf("Code %s not accepted at %d", c.code, c.unix)
+		} else if step != c.unix/totpPeriod {
+			t.Errorf("Got step %d, want %d", step, c.unix/totpPeriod)
+		}
+	}
+
+	if _, ok := ValidateTOTP(secret, "287082", time.Unix(59+totpPeriod*3, 0)); ok {
+		t.Error("Code accepted far outside its time window")
+	}
+	if _, ok := ValidateTOTP(secret, "000000", time.Unix(59, 0)); ok {
+		t.Error("Wrong code accepted")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("Unexpected recovery code format %q", code)
+		}
+		seen[HashRecoveryCode(code)] = true
+	}
+	if len(seen) != len(codes) {
+		t.Error("Recovery codes are not unique")
+	}
+	if HashRecoveryCode(" ABCDE-FGHIJ ") != HashRecoveryCode("abcdefghij") {
+		t.Error("Recovery code hashing should ignore case, dashes and spaces")
+	}
+}
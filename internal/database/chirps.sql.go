@@ -7,29 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, moderation_status)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, moderation_status
 `
 
 type CreateChirpParams struct {
-	Body   string    `json:"body"`
-	UserID uuid.UUID `json:"user_id"`
+	Body             string        `json:"body"`
+	UserID           uuid.UUID     `json:"user_id"`
+	InReplyTo        uuid.NullUUID `json:"in_reply_to"`
+	ModerationStatus string        `json:"moderation_status"`
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.ModerationStatus,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +48,8 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.ModerationStatus,
 	)
 	return i, err
 }
@@ -60,13 +73,35 @@ func (q *Queries) DropAllChirps(ctx context.Context) error {
 	return err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
-ORDER BY created_at ASC
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors (id, in_reply_to, depth) AS (
+    SELECT parent.id, parent.in_reply_to, 1
+    FROM chirps parent
+    JOIN chirps child ON child.in_reply_to = parent.id
+    WHERE child.id = $1
+    UNION ALL
+    SELECT chirps.id, chirps.in_reply_to, ancestors.depth + 1
+    FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.moderation_status FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+WHERE (chirps.moderation_status = 'visible' OR chirps.user_id = $2::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2::uuid)
+)
+ORDER BY ancestors.depth DESC
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+type GetChirpAncestorsParams struct {
+	ChirpID  uuid.UUID     `json:"chirp_id"`
+	ViewerID uuid.NullUUID `json:"viewer_id"`
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ChirpID, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +115,8 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.ModerationStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -94,14 +131,116 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getAllChirpsByAuthor = `-- name: GetAllChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-where user_id = $1
-ORDER BY created_at ASC
+const getChirpEngagement = `-- name: GetChirpEngagement :many
+SELECT chirps.id,
+    (SELECT COUNT(*) FROM likes WHERE likes.chirp_id = chirps.id) AS like_count,
+    (SELECT COUNT(*) FROM rechirps WHERE rechirps.chirp_id = chirps.id) AS rechirp_count,
+    EXISTS (
+        SELECT 1 FROM likes
+        WHERE likes.chirp_id = chirps.id AND likes.user_id = $1::uuid
+    ) AS liked_by_me
+FROM chirps
+WHERE chirps.id = ANY($2::uuid[])
+`
+
+type GetChirpEngagementParams struct {
+	ViewerID uuid.NullUUID `json:"viewer_id"`
+	ChirpIds []uuid.UUID   `json:"chirp_ids"`
+}
+
+type GetChirpEngagementRow struct {
+	ID           uuid.UUID `json:"id"`
+	LikeCount    int64     `json:"like_count"`
+	RechirpCount int64     `json:"rechirp_count"`
+	LikedByMe    bool      `json:"liked_by_me"`
+}
+
+func (q *Queries) GetChirpEngagement(ctx context.Context, arg GetChirpEngagementParams) ([]GetChirpEngagementRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpEngagement, arg.ViewerID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpEngagementRow
+	for rows.Next() {
+		var i GetChirpEngagementRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.LikedByMe,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSingleChirp = `-- name: GetSingleChirp :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, moderation_status FROM chirps
+where id = $1
+`
+
+func (q *Queries) GetSingleChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getSingleChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.ModerationStatus,
+	)
+	return i, err
+}
+
+const listChirpDescendantsAsc = `-- name: ListChirpDescendantsAsc :many
+WITH RECURSIVE descendants (id) AS (
+    SELECT chirps.id FROM chirps
+    WHERE chirps.in_reply_to = $1::uuid
+    UNION ALL
+    SELECT chirps.id FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.moderation_status FROM chirps
+JOIN descendants ON chirps.id = descendants.id
+WHERE (chirps.moderation_status = 'visible' OR chirps.user_id = $2::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2::uuid)
+)
+AND ($3::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > ($3::timestamp, $4::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $5
 `
 
-func (q *Queries) GetAllChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirpsByAuthor, userID)
+type ListChirpDescendantsAscParams struct {
+	ChirpID         uuid.UUID     `json:"chirp_id"`
+	ViewerID        uuid.NullUUID `json:"viewer_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        uuid.NullUUID `json:"cursor_id"`
+	RowLimit        int32         `json:"row_limit"`
+}
+
+func (q *Queries) ListChirpDescendantsAsc(ctx context.Context, arg ListChirpDescendantsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpDescendantsAsc,
+		arg.ChirpID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.RowLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +254,8 @@ func (q *Queries) GetAllChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.ModerationStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -129,13 +270,395 @@ func (q *Queries) GetAllChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([
 	return items, nil
 }
 
-const getSingleChirp = `-- name: GetSingleChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-where id = $1
+const listChirpDescendantsDesc = `-- name: ListChirpDescendantsDesc :many
+WITH RECURSIVE descendants (id) AS (
+    SELECT chirps.id FROM chirps
+    WHERE chirps.in_reply_to = $1::uuid
+    UNION ALL
+    SELECT chirps.id FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.moderation_status FROM chirps
+JOIN descendants ON chirps.id = descendants.id
+WHERE (chirps.moderation_status = 'visible' OR chirps.user_id = $2::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2::uuid)
+)
+AND ($3::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < ($3::timestamp, $4::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $5
 `
 
-func (q *Queries) GetSingleChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getSingleChirp, id)
+type ListChirpDescendantsDescParams struct {
+	ChirpID         uuid.UUID     `json:"chirp_id"`
+	ViewerID        uuid.NullUUID `json:"viewer_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        uuid.NullUUID `json:"cursor_id"`
+	RowLimit        int32         `json:"row_limit"`
+}
+
+func (q *Queries) ListChirpDescendantsDesc(ctx context.Context, arg ListChirpDescendantsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpDescendantsDesc,
+		arg.ChirpID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, moderation_status FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (moderation_status = 'visible' OR user_id = $2::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2::uuid)
+)
+AND ($1::uuid IS NOT NULL OR NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = $2::uuid AND mutes.muted_id = chirps.user_id
+))
+AND ($3::timestamp IS NULL
+    OR (created_at, id) > ($3::timestamp, $4::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type ListChirpsAscParams struct {
+	AuthorID        uuid.NullUUID `json:"author_id"`
+	ViewerID        uuid.NullUUID `json:"viewer_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        uuid.NullUUID `json:"cursor_id"`
+	RowLimit        int32         `json:"row_limit"`
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, moderation_status FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (moderation_status = 'visible' OR user_id = $2::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2::uuid)
+)
+AND ($1::uuid IS NOT NULL OR NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = $2::uuid AND mutes.muted_id = chirps.user_id
+))
+AND ($3::timestamp IS NULL
+    OR (created_at, id) < ($3::timestamp, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type ListChirpsDescParams struct {
+	AuthorID        uuid.NullUUID `json:"author_id"`
+	ViewerID        uuid.NullUUID `json:"viewer_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        uuid.NullUUID `json:"cursor_id"`
+	RowLimit        int32         `json:"row_limit"`
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHeldChirps = `-- name: ListHeldChirps :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, moderation_status FROM chirps
+WHERE moderation_status = 'pending'
+ORDER BY created_at, id
+LIMIT $1
+`
+
+func (q *Queries) ListHeldChirps(ctx context.Context, limit int32) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listHeldChirps, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserChirps = `-- name: ListUserChirps :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, moderation_status FROM chirps
+WHERE user_id = $1
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListUserChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listUserChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.moderation_status,
+    ts_rank(to_tsvector('english', chirps.body), query)::real AS rank,
+    ts_headline('english',
+        replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+        query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')::text AS snippet
+FROM chirps, to_tsquery('english', $1) query
+WHERE to_tsvector('english', chirps.body) @@ query
+AND ($2::uuid IS NULL OR chirps.user_id = $2::uuid)
+AND (chirps.moderation_status = 'visible' OR chirps.user_id = $3::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $3::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $3::uuid)
+)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type SearchChirpsParams struct {
+	Query    string        `json:"query"`
+	AuthorID uuid.NullUUID `json:"author_id"`
+	ViewerID uuid.NullUUID `json:"viewer_id"`
+	RowLimit int32         `json:"row_limit"`
+}
+
+type SearchChirpsRow struct {
+	Chirp   Chirp   `json:"chirp"`
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.ModerationStatus,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setChirpModerationStatus = `-- name: SetChirpModerationStatus :one
+UPDATE chirps
+SET moderation_status = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, moderation_status
+`
+
+type SetChirpModerationStatusParams struct {
+	ID               uuid.UUID `json:"id"`
+	ModerationStatus string    `json:"moderation_status"`
+}
+
+func (q *Queries) SetChirpModerationStatus(ctx context.Context, arg SetChirpModerationStatusParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpModerationStatus, arg.ID, arg.ModerationStatus)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.ModerationStatus,
+	)
+	return i, err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+WITH revision AS (
+    INSERT INTO chirp_revisions (chirp_id, body)
+    SELECT chirps.id, chirps.body FROM chirps
+    WHERE chirps.id = $1
+)
+UPDATE chirps
+SET body = $2,
+    moderation_status = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, moderation_status
+`
+
+type UpdateChirpBodyParams struct {
+	ID               uuid.UUID `json:"id"`
+	Body             string    `json:"body"`
+	ModerationStatus string    `json:"moderation_status"`
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body, arg.ModerationStatus)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -143,6 +666,8 @@ func (q *Queries) GetSingleChirp(ctx context.Context, id uuid.UUID) (Chirp, erro
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.ModerationStatus,
 	)
 	return i, err
 }
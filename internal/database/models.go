@@ -11,28 +11,184 @@ import (
 	"github.com/google/uuid"
 )
 
+type Block struct {
+	BlockerID uuid.UUID `json:"blocker_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Chirp struct {
+	ID               uuid.UUID     `json:"id"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+	Body             string        `json:"body"`
+	UserID           uuid.UUID     `json:"user_id"`
+	InReplyTo        uuid.NullUUID `json:"in_reply_to"`
+	ModerationStatus string        `json:"moderation_status"`
+}
+
+type ChirpRevision struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
 	Body      string    `json:"body"`
+}
+
+type EmailVerification struct {
+	TokenHash string    `json:"token_hash"`
+	CreatedAt time.Time `json:"created_at"`
 	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
-type RefreshToken struct {
-	Token     string       `json:"token"`
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Like struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LockoutEvent struct {
+	ID          uuid.UUID     `json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	ThrottleKey string        `json:"throttle_key"`
+	UserID      uuid.NullUUID `json:"user_id"`
+	IpAddress   string        `json:"ip_address"`
+	Failures    int32         `json:"failures"`
+	LockedUntil time.Time     `json:"locked_until"`
+}
+
+type LoginAttempt struct {
+	ThrottleKey   string       `json:"throttle_key"`
+	Failures      int32        `json:"failures"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+	LockedUntil   sql.NullTime `json:"locked_until"`
+}
+
+type LoginChallenge struct {
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Attempts  int32     `json:"attempts"`
+}
+
+type Medium struct {
+	ID           uuid.UUID     `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UserID       uuid.UUID     `json:"user_id"`
+	ChirpID      uuid.NullUUID `json:"chirp_id"`
+	ContentType  string        `json:"content_type"`
+	Width        int32         `json:"width"`
+	Height       int32         `json:"height"`
+	BlobKey      string        `json:"blob_key"`
+	ThumbnailKey string        `json:"thumbnail_key"`
+	Kind         string        `json:"kind"`
+}
+
+type ModerationHit struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	RuleID    uuid.UUID     `json:"rule_id"`
+	UserID    uuid.UUID     `json:"user_id"`
+	ChirpID   uuid.NullUUID `json:"chirp_id"`
+	Action    string        `json:"action"`
+	Matched   string        `json:"matched"`
+}
+
+type ModerationRule struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason"`
+}
+
+type ModerationWord struct {
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Mute struct {
+	MuterID   uuid.UUID `json:"muter_id"`
+	MutedID   uuid.UUID `json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PasswordReset struct {
+	TokenHash string       `json:"token_hash"`
 	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
 	UserID    uuid.UUID    `json:"user_id"`
 	ExpiresAt time.Time    `json:"expires_at"`
-	RevokedAt sql.NullTime `json:"revoked_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+}
+
+type Rechirp struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID    `json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
+	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+}
+
+type RefreshToken struct {
+	Token      string         `json:"token"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	UserID     uuid.UUID      `json:"user_id"`
+	ExpiresAt  time.Time      `json:"expires_at"`
+	RevokedAt  sql.NullTime   `json:"revoked_at"`
+	FamilyID   uuid.UUID      `json:"family_id"`
+	ReplacedBy sql.NullString `json:"replaced_by"`
+	UserAgent  string         `json:"user_agent"`
+	IpAddress  string         `json:"ip_address"`
+}
+
+type Report struct {
+	ID         uuid.UUID      `json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	ChirpID    uuid.NullUUID  `json:"chirp_id"`
+	AuthorID   uuid.UUID      `json:"author_id"`
+	ReporterID uuid.UUID      `json:"reporter_id"`
+	Reason     string         `json:"reason"`
+	Details    string         `json:"details"`
+	ResolvedAt sql.NullTime   `json:"resolved_at"`
+	ResolvedBy uuid.NullUUID  `json:"resolved_by"`
+	Resolution sql.NullString `json:"resolution"`
 }
 
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
-	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ID                  uuid.UUID      `json:"id"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	Email               string         `json:"email"`
+	HashedPassword      string         `json:"hashed_password"`
+	IsChirpyRed         bool           `json:"is_chirpy_red"`
+	Roles               []string       `json:"roles"`
+	TotpSecret          sql.NullString `json:"-"`
+	TotpEnabledAt       sql.NullTime   `json:"totp_enabled_at"`
+	TotpLastStep        int64          `json:"-"`
+	EmailVerifiedAt     sql.NullTime   `json:"email_verified_at"`
+	PendingEmail        sql.NullString `json:"pending_email"`
+	Handle              string         `json:"handle"`
+	DisplayName         string         `json:"display_name"`
+	Bio                 string         `json:"bio"`
+	AvatarUrl           string         `json:"avatar_url"`
+	DeletionRequestedAt sql.NullTime   `json:"deletion_requested_at"`
+	SuspendedUntil      sql.NullTime   `json:"suspended_until"`
+	SuspensionReason    string         `json:"suspension_reason"`
+	BannedAt            sql.NullTime   `json:"banned_at"`
 }
@@ -7,29 +7,132 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const banUser = `-- name: BanUser :one
+UPDATE users
+SET banned_at = NOW(),
+    suspension_reason = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at
+`
+
+type BanUserParams struct {
+	ID               uuid.UUID `json:"id"`
+	SuspensionReason string    `json:"suspension_reason"`
+}
+
+func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, banUser, arg.ID, arg.SuspensionReason)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
+const cancelUserDeletion = `-- name: CancelUserDeletion :exec
+UPDATE users
+SET deletion_requested_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, cancelUserDeletion, id)
+	return err
+}
+
+const confirmUserEmail = `-- name: ConfirmUserEmail :one
+UPDATE users
+SET email = $2,
+    pending_email = CASE WHEN pending_email = $2 THEN NULL ELSE pending_email END,
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+AND (email = $2 OR pending_email = $2)
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at
+`
+
+type ConfirmUserEmailParams struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+func (q *Queries) ConfirmUserEmail(ctx context.Context, arg ConfirmUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, confirmUserEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at
 `
 
 type CreateUserParams struct {
 	Email          string `json:"email"`
 	HashedPassword string `json:"hashed_password"`
+	Handle         string `json:"handle"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,10 +141,38 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
 	)
 	return i, err
 }
 
+const disableTOTP = `-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_step = 0,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableTOTP, id)
+	return err
+}
+
 const dropAllUsers = `-- name: DropAllUsers :exec
 TRUNCATE TABLE users CASCADE
 `
@@ -51,8 +182,84 @@ func (q *Queries) DropAllUsers(ctx context.Context) error {
 	return err
 }
 
+const enableTOTP = `-- name: EnableTOTP :exec
+UPDATE users
+SET totp_enabled_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) EnableTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, enableTOTP, id)
+	return err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at FROM users WHERE lower(handle) = lower($1)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
 const getUserFromEmail = `-- name: GetUserFromEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserFromEmail(ctx context.Context, email string) (User, error) {
@@ -65,26 +272,226 @@ func (q *Queries) GetUserFromEmail(ctx context.Context, email string) (User, err
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
 	)
 	return i, err
 }
 
-const updateUserEmailPassword = `-- name: UpdateUserEmailPassword :one
+const getUserStanding = `-- name: GetUserStanding :one
+SELECT banned_at, suspended_until, suspension_reason FROM users
+WHERE id = $1
+`
+
+type GetUserStandingRow struct {
+	BannedAt         sql.NullTime `json:"banned_at"`
+	SuspendedUntil   sql.NullTime `json:"suspended_until"`
+	SuspensionReason string       `json:"suspension_reason"`
+}
+
+func (q *Queries) GetUserStanding(ctx context.Context, id uuid.UUID) (GetUserStandingRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserStanding, id)
+	var i GetUserStandingRow
+	err := row.Scan(
+		&i.BannedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+	)
+	return i, err
+}
+
+const liftUserSuspension = `-- name: LiftUserSuspension :one
 UPDATE users
-SET email = $2,
-    hashed_password = $3
+SET suspended_until = NULL,
+    banned_at = NULL,
+    suspension_reason = '',
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at
+`
+
+func (q *Queries) LiftUserSuspension(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, liftUserSuspension, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
+const listUserProfiles = `-- name: ListUserProfiles :many
+SELECT id, handle, display_name, bio, avatar_url, created_at FROM users
+WHERE id = ANY($1::uuid[])
+`
+
+type ListUserProfilesRow struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarUrl   string    `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (q *Queries) ListUserProfiles(ctx context.Context, userIds []uuid.UUID) ([]ListUserProfilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserProfiles, pq.Array(userIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserProfilesRow
+	for rows.Next() {
+		var i ListUserProfilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedUsers = `-- name: PurgeDeletedUsers :many
+DELETE FROM users
+WHERE deletion_requested_at < $1
+RETURNING id
+`
+
+func (q *Queries) PurgeDeletedUsers(ctx context.Context, deletionRequestedAt sql.NullTime) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, purgeDeletedUsers, deletionRequestedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :exec
+UPDATE users
+SET hashed_password = $2
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+AND hashed_password = $3
 `
 
-type UpdateUserEmailPasswordParams struct {
+type RehashUserPasswordParams struct {
 	ID             uuid.UUID `json:"id"`
-	Email          string    `json:"email"`
 	HashedPassword string    `json:"hashed_password"`
+	OldHash        string    `json:"old_hash"`
+}
+
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, rehashUserPassword, arg.ID, arg.HashedPassword, arg.OldHash)
+	return err
+}
+
+const requestUserDeletion = `-- name: RequestUserDeletion :one
+UPDATE users
+SET deletion_requested_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at
+`
+
+func (q *Queries) RequestUserDeletion(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, requestUserDeletion, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
+const setPendingEmail = `-- name: SetPendingEmail :one
+UPDATE users
+SET pending_email = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at
+`
+
+type SetPendingEmailParams struct {
+	ID           uuid.UUID      `json:"id"`
+	PendingEmail sql.NullString `json:"pending_email"`
 }
 
-func (q *Queries) UpdateUserEmailPassword(ctx context.Context, arg UpdateUserEmailPasswordParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUserEmailPassword, arg.ID, arg.Email, arg.HashedPassword)
+func (q *Queries) SetPendingEmail(ctx context.Context, arg SetPendingEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setPendingEmail, arg.ID, arg.PendingEmail)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -93,10 +500,203 @@ func (q *Queries) UpdateUserEmailPassword(ctx context.Context, arg UpdateUserEma
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
 	)
 	return i, err
 }
 
+const setTOTPSecret = `-- name: SetTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2,
+    totp_enabled_at = NULL,
+    totp_last_step = 0,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetTOTPSecretParams struct {
+	ID         uuid.UUID      `json:"id"`
+	TotpSecret sql.NullString `json:"totp_secret"`
+}
+
+func (q *Queries) SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setTOTPSecret, arg.ID, arg.TotpSecret)
+	return err
+}
+
+const setUserRoles = `-- name: SetUserRoles :one
+UPDATE users
+SET roles = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at
+`
+
+type SetUserRolesParams struct {
+	ID    uuid.UUID `json:"id"`
+	Roles []string  `json:"roles"`
+}
+
+func (q *Queries) SetUserRoles(ctx context.Context, arg SetUserRolesParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRoles, arg.ID, pq.Array(arg.Roles))
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET suspended_until = $2,
+    suspension_reason = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at
+`
+
+type SuspendUserParams struct {
+	ID               uuid.UUID    `json:"id"`
+	SuspendedUntil   sql.NullTime `json:"suspended_until"`
+	SuspensionReason string       `json:"suspension_reason"`
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, arg.ID, arg.SuspendedUntil, arg.SuspensionReason)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
+const updateUserFields = `-- name: UpdateUserFields :one
+UPDATE users
+SET pending_email = COALESCE($1, pending_email),
+    hashed_password = COALESCE($2, hashed_password),
+    handle = COALESCE($3, handle),
+    display_name = COALESCE($4, display_name),
+    bio = COALESCE($5, bio),
+    avatar_url = COALESCE($6, avatar_url),
+    updated_at = NOW()
+WHERE id = $7
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, roles, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, handle, display_name, bio, avatar_url, deletion_requested_at, suspended_until, suspension_reason, banned_at
+`
+
+type UpdateUserFieldsParams struct {
+	PendingEmail   sql.NullString `json:"pending_email"`
+	HashedPassword sql.NullString `json:"hashed_password"`
+	Handle         sql.NullString `json:"handle"`
+	DisplayName    sql.NullString `json:"display_name"`
+	Bio            sql.NullString `json:"bio"`
+	AvatarUrl      sql.NullString `json:"avatar_url"`
+	ID             uuid.UUID      `json:"id"`
+}
+
+func (q *Queries) UpdateUserFields(ctx context.Context, arg UpdateUserFieldsParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserFields,
+		arg.PendingEmail,
+		arg.HashedPassword,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarUrl,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		pq.Array(&i.Roles),
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.DeletionRequestedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID `json:"id"`
+	HashedPassword string    `json:"hashed_password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
 const upgradeUser = `-- name: UpgradeUser :exec
 UPDATE users
 SET is_chirpy_red = TRUE
@@ -107,3 +707,23 @@ func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, upgradeUser, id)
 	return err
 }
+
+const useTOTPStep = `-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1
+AND totp_last_step < $2
+`
+
+type UseTOTPStepParams struct {
+	ID           uuid.UUID `json:"id"`
+	TotpLastStep int64     `json:"totp_last_step"`
+}
+
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useTOTPStep, arg.ID, arg.TotpLastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := FromEnv("", "", "", "chirpy@example.com", dir)
+	err := m.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Reset your password",
+		Body:    "line one\nline two",
+	})
+	if err != nil {
+		t.Fatalf("Could not send mail: %s", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("Expected one mail in %s, got %d", dir, len(entries))
+	}
+	data, _ := os.ReadFile(dir + "/" + entries[0].Name())
+	for _, want := range []string{"To: user@example.com\r\n", "Subject: Reset your password\r\n", "line one\r\nline two"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Mail is missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestSMTPMailerHonorsContext(t *testing.T) {
+	// A server that accepts the connection but never sends its greeting.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	m := &SMTPMailer{Addr: l.Addr().String(), From: "chirpy@example.com"}
+	err = m.Send(ctx, Message{To: "user@example.com", Subject: "Hi", Body: "Hi"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to abort Send, got %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	ErrUnsupportedType = errors.New("only JPEG, PNG and GIF images are supported")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+// MaxPixels guards against decompression bombs: small files that decode to
+// huge images. 16 megapixels covers phone photos, which are scaled down to
+// 2048px anyway, and decodes to at most 64 MB.
+const MaxPixels = 16_000_000
+
+// Options controls how Process resizes an upload.
+type Options struct {
+	MaxDimension  int  // longest side of the stored image
+	ThumbnailSize int  // longest side of the thumbnail
+	Square        bool // crop to the centre square first, for avatars
+}
+
+var (
+	ChirpImage = Options{MaxDimension: 2048, ThumbnailSize: 320}
+	Avatar     = Options{MaxDimension: 512, ThumbnailSize: 128, Square: true}
+)
+
+type Image struct {
+	Data          []byte
+	ContentType   string
+	Width, Height int
+}
+
+type Processed struct {
+	Full      Image
+	Thumbnail Image
+}
+
+// Process checks that data is a supported image, applies its EXIF
+// orientation and re-encodes it, which drops EXIF and any other metadata
+// such as GPS positions. JPEGs stay JPEGs, everything else becomes PNG.
+func Process(data []byte, opts Options) (*Processed, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if opts.Square {
+		src = cropSquare(src)
+	}
+	full := orient(scale(src, opts.MaxDimension), jpegOrientation(data))
+	thumb := scale(full, opts.ThumbnailSize)
+
+	res := &Processed{}
+	if res.Full, err = encode(full, format); err != nil {
+		return nil, err
+	}
+	if res.Thumbnail, err = encode(thumb, format); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func encode(img image.Image, format string) (Image, error) {
+	var buf bytes.Buffer
+	res := Image{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if format == "jpeg" {
+		res.ContentType = "image/jpeg"
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		res.Data = buf.Bytes()
+		return res, err
+	}
+	res.ContentType = "image/png"
+	err := png.Encode(&buf, img)
+	res.Data = buf.Bytes()
+	return res, err
+}
+
+func cropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	dst := image.NewNRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), img, image.Pt(x, y), draw.Src)
+	return dst
+}
+
+// scale shrinks img so its longest side is at most limit. Smaller images
+// are only copied.
+func scale(img image.Image, limit int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > limit || h > limit {
+		if w >= h {
+			w, h = limit, max(1, h*limit/w)
+		} else {
+			w, h = max(1, w*limit/h), limit
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// orient turns img upright according to an EXIF orientation value (1-8).
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored upside down
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs a clockwise turn
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs a counter-clockwise turn
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG. It returns 1,
+// meaning no change, if there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := range count {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
@@ -0,0 +1,117 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.NRGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an APP1 segment with only an orientation tag
+// right after the SOI marker of a JPEG.
+func withOrientation(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+	return append(append([]byte{0xFF, 0xD8}, app1...), jpg[2:]...)
+}
+
+func TestProcessOrientsAndStripsExif(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(40, 20), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := withOrientation(t, buf.Bytes(), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("got orientation %d, want 6", got)
+	}
+	res, err := Process(data, ChirpImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Full.Width != 20 || res.Full.Height != 40 {
+		t.Errorf("got %dx%d, want the image turned to 20x40", res.Full.Width, res.Full.Height)
+	}
+	if res.Full.ContentType != "image/jpeg" {
+		t.Errorf("got content type %s", res.Full.ContentType)
+	}
+	if bytes.Contains(res.Full.Data, []byte("Exif")) {
+		t.Error("EXIF data was not stripped")
+	}
+}
+
+func TestProcessThumbnails(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(1000, 500))
+	res, err := Process(buf.Bytes(), ChirpImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Thumbnail.Width != 320 || res.Thumbnail.Height != 160 {
+		t.Errorf("got thumbnail %dx%d, want 320x160", res.Thumbnail.Width, res.Thumbnail.Height)
+	}
+	res, err = Process(buf.Bytes(), Avatar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Full.Width != 500 || res.Full.Height != 500 || res.Thumbnail.Width != 128 {
+		t.Errorf("avatar should be square, got %dx%d", res.Full.Width, res.Full.Height)
+	}
+}
+
+func TestProcessRejectsOtherFiles(t *testing.T) {
+	if _, err := Process([]byte("<svg onload=alert(1)>"), ChirpImage); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+}
+
+func TestDiskStore(t *testing.T) {
+	ctx := context.Background()
+	store := &DiskStore{Dir: t.TempDir()}
+	if err := store.Put(ctx, "a.png", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	f, err := store.Open(ctx, "a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(f)
+	f.Close()
+	if string(got) != "data" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := store.Open(ctx, "missing.png"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want fs.ErrNotExist", err)
+	}
+	for _, key := range []string{"../a.png", "sub/a.png", ".hidden", ""} {
+		if err := store.Put(ctx, key, nil); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: got %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
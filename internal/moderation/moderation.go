@@ -0,0 +1,227 @@
+// Package moderation checks text against word, regex and link domain rules
+// and decides what happens to it: masking, holding it for review, hiding it
+// or rejecting it. Words are compared after Unicode normalization, so case,
+// accents, lookalike letters and leetspeak do not get a word past a rule.
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const Replacement = "****"
+
+// DefaultWords is the list used when nothing else is configured.
+var DefaultWords = Words{"kerfuffle", "sharbert", "fornax"}
+
+// confusables maps lookalike letters from other scripts and common leetspeak
+// digits and symbols to the latin letter they imitate.
+var confusables = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l',
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i',
+	'ј': 'j', 'ԁ': 'd',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// Normalize folds s to the form list words and text are compared in:
+// compatibility decomposition (NFKD, so full-width and ligature forms become
+// plain letters), accents removed, case folded, lookalikes mapped, then
+// recomposed with NFC.
+func Normalize(s string) string {
+	t := transform.Chain(
+		norm.NFKD,
+		runes.Remove(runes.In(unicode.Mn)),
+		cases.Fold(),
+		runes.Map(func(r rune) rune {
+			if m, ok := confusables[r]; ok {
+				return m
+			}
+			return r
+		}),
+		norm.NFC,
+	)
+	res, _, err := transform.String(t, s)
+	if err != nil {
+		return strings.ToLower(s)
+	}
+	return res
+}
+
+// isWordRune reports whether r can be part of a word. Leetspeak symbols
+// count, so "k3rfuffl3" and "$harbert" are single words.
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+		return true
+	}
+	_, ok := confusables[r]
+	return ok && !unicode.IsSpace(r)
+}
+
+// scanWords calls fn with the byte offsets and normalized form of every word
+// in text.
+func scanWords(text string, fn func(start, end int, word string)) {
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		// Trailing symbols like "!" end a sentence more often than they
+		// stand for a letter.
+		word := strings.TrimRightFunc(text[start:end], func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+		})
+		if n := Normalize(word); n != "" {
+			fn(start, start+len(word), n)
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+		} else {
+			flush(i)
+		}
+	}
+	flush(len(text))
+}
+
+// Source loads the current word list.
+type Source interface {
+	Load(ctx context.Context) ([]string, error)
+}
+
+// Words is a fixed list.
+type Words []string
+
+func (w Words) Load(ctx context.Context) ([]string, error) {
+	return w, nil
+}
+
+// Sources combines several sources into one list. Loading fails if any of
+// them fails.
+type Sources []Source
+
+func (s Sources) Load(ctx context.Context) ([]string, error) {
+	var words []string
+	for _, source := range s {
+		w, err := source.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, w...)
+	}
+	return words, nil
+}
+
+// FileSource reads one word per line. Empty lines and lines starting with #
+// are skipped.
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) Load(ctx context.Context) ([]string, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
+
+// RuleSource loads the current moderation rules.
+type RuleSource interface {
+	Rules(ctx context.Context) ([]Rule, error)
+}
+
+// Moderator serves the Engine built from its sources and swaps in a new one
+// on every Reload, so the rules can change while the server runs. Words from
+// the word list are masked.
+type Moderator struct {
+	words  Source
+	rules  RuleSource
+	engine atomic.Pointer[Engine]
+}
+
+// New loads the word list and, if rules is not nil, the rules once. It fails
+// if the first load fails.
+func New(ctx context.Context, words Source, rules RuleSource) (*Moderator, error) {
+	m := &Moderator{words: words, rules: rules}
+	if err := m.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload replaces the engine with one built from a fresh load of the
+// sources. On error the previous engine stays in place.
+func (m *Moderator) Reload(ctx context.Context) error {
+	words, err := m.words.Load(ctx)
+	if err != nil {
+		return err
+	}
+	rules := WordRules(words, ActionMask)
+	if m.rules != nil {
+		more, err := m.rules.Rules(ctx)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, more...)
+	}
+	e, err := NewEngine(rules)
+	if err != nil {
+		return err
+	}
+	m.engine.Store(e)
+	return nil
+}
+
+// Watch reloads the rules every interval until ctx is done.
+func (m *Moderator) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Reload(ctx); err != nil {
+				log.Printf("Error reloading moderation rules: %s", err)
+			}
+		}
+	}
+}
+
+func (m *Moderator) Engine() *Engine {
+	return m.engine.Load()
+}
+
+func (m *Moderator) Evaluate(text string) Verdict {
+	return m.Engine().Evaluate(text)
+}
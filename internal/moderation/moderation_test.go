@@ -0,0 +1,82 @@
+package moderation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mustEngine(t *testing.T, rules []Rule) *Engine {
+	t.Helper()
+	e, err := NewEngine(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestCensor(t *testing.T) {
+	e := mustEngine(t, WordRules(DefaultWords, ActionMask))
+	cases := []struct {
+		in, want string
+	}{
+		{"I had a kerfuffle today", "I had a **** today"},
+		{"What a KERFUFFLE!", "What a ****!"},
+		{"Fornax and fórnax", "**** and ****"},
+		{"k3rfuffl3 and $harbert", "**** and ****"},
+		{"kеrfuffle with a Cyrillic е", "**** with a Cyrillic е"},
+		{"ｆｏｒｎａｘ", "****"},
+		{"fornaxes are fine, so is sharbertine", "fornaxes are fine, so is sharbertine"},
+		{"(sharbert)", "(****)"},
+		{"nothing to see", "nothing to see"},
+	}
+	for _, c := range cases {
+		if got := e.Evaluate(c.in).Text; got != c.want {
+			t.Errorf("Censor(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	e := mustEngine(t, WordRules([]string{"Kerfuffle", " Straße "}, ActionMask))
+	hits := e.Evaluate("KERFUFFLE in der strasse").Hits
+	if len(hits) != 2 || hits[0].Match != "KERFUFFLE" || hits[1].Match != "strasse" {
+		t.Errorf("got %v", hits)
+	}
+}
+
+func TestModeratorReload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("# banned\nkerfuffle\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(ctx, FileSource{Path: path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Evaluate("kerfuffle sharbert").Text; got != "**** sharbert" {
+		t.Errorf("got %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("sharbert\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Evaluate("kerfuffle sharbert").Text; got != "kerfuffle ****" {
+		t.Errorf("after reload got %q", got)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reload(ctx); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	if got := m.Evaluate("sharbert").Text; got != "****" {
+		t.Errorf("failed reload should keep the old list, got %q", got)
+	}
+}
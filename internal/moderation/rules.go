@@ -0,0 +1,241 @@
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Kind says how a rule's pattern is matched.
+type Kind string
+
+const (
+	KindWord   Kind = "word"   // a whole word, compared after Normalize
+	KindRegex  Kind = "regex"  // an RE2 expression run on the raw text
+	KindDomain Kind = "domain" // links to the domain or any of its subdomains
+)
+
+// Action is what happens to text a rule matches.
+type Action string
+
+const (
+	ActionNone   Action = ""
+	ActionMask   Action = "mask"   // replace the match with Replacement
+	ActionHold   Action = "hold"   // keep it from everyone but the author until reviewed
+	ActionHide   Action = "hide"   // show it to its author only, without telling them
+	ActionReject Action = "reject" // refuse it
+)
+
+// severity orders actions from mildest to harshest.
+var severity = map[Action]int{
+	ActionNone:   0,
+	ActionMask:   1,
+	ActionHold:   2,
+	ActionHide:   3,
+	ActionReject: 4,
+}
+
+// Stricter reports whether a is harsher than b.
+func (a Action) Stricter(b Action) bool {
+	return severity[a] > severity[b]
+}
+
+// MaxPatternLength bounds rule patterns. RE2 runs in linear time, but a
+// long expression still costs on every chirp.
+const MaxPatternLength = 256
+
+var ErrInvalidRule = errors.New("invalid moderation rule")
+
+// Rule maps a pattern to an action. Reason is shown to the author when a
+// chirp is rejected.
+type Rule struct {
+	ID      uuid.UUID // zero for words from a word list
+	Kind    Kind
+	Pattern string
+	Action  Action
+	Reason  string
+}
+
+// WordRules turns a word list into rules with the same action.
+func WordRules(words []string, action Action) []Rule {
+	rules := make([]Rule, 0, len(words))
+	for _, w := range words {
+		rules = append(rules, Rule{Kind: KindWord, Pattern: w, Action: action})
+	}
+	return rules
+}
+
+// Validate checks the rule can be compiled. Errors wrap ErrInvalidRule.
+func (r Rule) Validate() error {
+	if _, ok := severity[r.Action]; !ok || r.Action == ActionNone {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidRule, r.Action)
+	}
+	pattern := strings.TrimSpace(r.Pattern)
+	if pattern == "" || len(pattern) > MaxPatternLength {
+		return fmt.Errorf("%w: pattern must be 1 to %d bytes", ErrInvalidRule, MaxPatternLength)
+	}
+	switch r.Kind {
+	case KindWord:
+		if strings.ContainsFunc(Normalize(pattern), unicode.IsSpace) {
+			return fmt.Errorf("%w: a word rule matches a single word", ErrInvalidRule)
+		}
+	case KindRegex:
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidRule, err)
+		}
+	case KindDomain:
+		if d := NormalizeDomain(pattern); !strings.Contains(d, ".") || strings.ContainsAny(d, " /@") {
+			return fmt.Errorf("%w: %q is not a domain", ErrInvalidRule, pattern)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidRule, r.Kind)
+	}
+	return nil
+}
+
+// NormalizeDomain lowercases a domain and strips a scheme, path or trailing
+// dot, so "https://Example.com/" and "example.com" are the same rule.
+func NormalizeDomain(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+	if i := strings.IndexAny(s, "/?#:"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSuffix(s, ".")
+}
+
+// linkPattern finds host names in text, with or without a scheme, port or
+// path. Group 1 is the host.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://)?((?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,61}[a-z0-9])\b(?::\d+)?(?:[/?#]\S*)?`)
+
+// Hit is one match of a rule.
+type Hit struct {
+	Rule  Rule
+	Match string
+}
+
+// Verdict is the outcome of Evaluate.
+type Verdict struct {
+	Text   string // the input with every masked match replaced
+	Action Action // the harshest action of all hits
+	Reason string // the reason given by the rule that decided Action
+	Hits   []Hit
+}
+
+type regexRule struct {
+	Rule
+	re *regexp.Regexp
+}
+
+// Engine is an immutable, compiled rule set.
+type Engine struct {
+	words   map[string][]Rule
+	regexps []regexRule
+	domains []Rule
+	n       int
+}
+
+// NewEngine compiles rules. Rules with an empty pattern, such as blank lines
+// in a word list, are skipped; any other invalid rule is an error.
+func NewEngine(rules []Rule) (*Engine, error) {
+	e := &Engine{words: make(map[string][]Rule)}
+	for _, r := range rules {
+		if strings.TrimSpace(r.Pattern) == "" {
+			continue
+		}
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		switch r.Kind {
+		case KindWord:
+			w := Normalize(strings.TrimSpace(r.Pattern))
+			e.words[w] = append(e.words[w], r)
+		case KindRegex:
+			e.regexps = append(e.regexps, regexRule{Rule: r, re: regexp.MustCompile(r.Pattern)})
+		case KindDomain:
+			r.Pattern = NormalizeDomain(r.Pattern)
+			e.domains = append(e.domains, r)
+		}
+		e.n++
+	}
+	return e, nil
+}
+
+// Len returns the number of rules in the engine.
+func (e *Engine) Len() int {
+	return e.n
+}
+
+// Evaluate runs every rule against text.
+func (e *Engine) Evaluate(text string) Verdict {
+	v := Verdict{Text: text}
+	var masks [][2]int
+	hit := func(r Rule, start, end int) {
+		v.Hits = append(v.Hits, Hit{Rule: r, Match: text[start:end]})
+		if r.Action == ActionMask {
+			masks = append(masks, [2]int{start, end})
+		}
+		if r.Action.Stricter(v.Action) {
+			v.Action, v.Reason = r.Action, r.Reason
+		}
+	}
+
+	if len(e.words) > 0 {
+		scanWords(text, func(start, end int, word string) {
+			for _, r := range e.words[word] {
+				hit(r, start, end)
+			}
+		})
+	}
+	for _, r := range e.regexps {
+		for _, m := range r.re.FindAllStringIndex(text, -1) {
+			if m[0] < m[1] {
+				hit(r.Rule, m[0], m[1])
+			}
+		}
+	}
+	if len(e.domains) > 0 {
+		for _, m := range linkPattern.FindAllStringSubmatchIndex(text, -1) {
+			host := strings.ToLower(text[m[2]:m[3]])
+			// Sentence punctuation after a link is not part of it.
+			end := m[0] + len(strings.TrimRight(text[m[0]:m[1]], ".,;:!?)]}'\""))
+			for _, r := range e.domains {
+				if host == r.Pattern || strings.HasSuffix(host, "."+r.Pattern) {
+					hit(r, m[0], end)
+				}
+			}
+		}
+	}
+
+	if len(masks) > 0 {
+		v.Text = mask(text, masks)
+	}
+	return v
+}
+
+// mask replaces each span with Replacement. Overlapping spans, such as a
+// word inside a masked link, are merged first.
+func mask(text string, spans [][2]int) string {
+	slices.SortFunc(spans, func(a, b [2]int) int {
+		return a[0] - b[0]
+	})
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s[1] <= last {
+			continue
+		}
+		if s[0] >= last {
+			b.WriteString(text[last:s[0]])
+			b.WriteString(Replacement)
+		}
+		last = s[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package moderation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEvaluateActions(t *testing.T) {
+	e := mustEngine(t, []Rule{
+		{Kind: KindWord, Pattern: "kerfuffle", Action: ActionMask},
+		{Kind: KindWord, Pattern: "fornax", Action: ActionHold},
+		{Kind: KindRegex, Pattern: `(?i)buy\s+followers`, Action: ActionReject, Reason: "no spam"},
+		{Kind: KindDomain, Pattern: "https://Spam.example/", Action: ActionHide},
+		{Kind: KindDomain, Pattern: "ads.test", Action: ActionMask},
+	})
+	cases := []struct {
+		in     string
+		text   string
+		action Action
+		reason string
+		hits   int
+	}{
+		{"all good", "all good", ActionNone, "", 0},
+		{"a Kerfuffle", "a ****", ActionMask, "", 1},
+		{"kerfuffle about Fornax", "**** about Fornax", ActionHold, "", 2},
+		{"BUY  followers now", "BUY  followers now", ActionReject, "no spam", 1},
+		{"see www.spam.example/x?y=1.", "see www.spam.example/x?y=1.", ActionHide, "", 1},
+		{"notspam.example is fine", "notspam.example is fine", ActionNone, "", 0},
+		{"go to https://ads.test/kerfuffle, now", "go to ****, now", ActionMask, "", 2},
+		{"fornax, buy followers at spam.example", "fornax, buy followers at spam.example", ActionReject, "no spam", 3},
+	}
+	for _, c := range cases {
+		v := e.Evaluate(c.in)
+		if v.Text != c.text || v.Action != c.action || v.Reason != c.reason || len(v.Hits) != c.hits {
+			t.Errorf("Evaluate(%q) = %q %q %q %d hits, want %q %q %q %d hits",
+				c.in, v.Text, v.Action, v.Reason, len(v.Hits), c.text, c.action, c.reason, c.hits)
+		}
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	bad := []Rule{
+		{Kind: KindWord, Pattern: "two words", Action: ActionMask},
+		{Kind: KindWord, Pattern: "ok", Action: "ban"},
+		{Kind: KindRegex, Pattern: "(", Action: ActionReject},
+		{Kind: KindDomain, Pattern: "localhost", Action: ActionHide},
+		{Kind: "phrase", Pattern: "x", Action: ActionMask},
+		{Kind: KindWord, Pattern: " ", Action: ActionMask},
+	}
+	for _, r := range bad {
+		if err := r.Validate(); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidRule", r, err)
+		}
+	}
+	if _, err := NewEngine(bad[2:3]); err == nil {
+		t.Error("NewEngine accepted an invalid regex")
+	}
+}
+
+func TestActionStricter(t *testing.T) {
+	order := []Action{ActionNone, ActionMask, ActionHold, ActionHide, ActionReject}
+	for i := 1; i < len(order); i++ {
+		if !order[i].Stricter(order[i-1]) || order[i-1].Stricter(order[i]) {
+			t.Errorf("%q should be stricter than %q", order[i], order[i-1])
+		}
+	}
+}
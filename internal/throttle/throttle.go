@@ -0,0 +1,170 @@
+// Package throttle slows down repeated failures, such as wrong passwords,
+// with exponential backoff and temporary lockouts.
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Record is the failure history kept for one key, e.g. an email address or
+// a client IP.
+type Record struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Store persists records. Get returns the zero Record for unknown keys.
+//
+// Increment and Lock must be atomic: parallel attempts for one key have to
+// count as that many failures, and only one of them may start a lockout.
+type Store interface {
+	Get(ctx context.Context, key string) (Record, error)
+	// Increment records a failure at now and returns the updated record.
+	// Failures before expiredBefore are forgotten first, unless the key is
+	// still locked.
+	Increment(ctx context.Context, key string, now, expiredBefore time.Time) (Record, error)
+	// Lock locks key until the given time unless it is already locked at
+	// now, and reports whether it did.
+	Lock(ctx context.Context, key string, until, now time.Time) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Limiter decides when the next attempt for a key is allowed.
+//
+// The first FreeAttempts failures cost nothing. Every failure after that
+// doubles the wait before the next attempt, starting at BaseDelay and capped
+// at MaxDelay. Reaching LockoutThreshold failures locks the key for
+// LockoutDuration. Failures are forgotten after Window without any new ones.
+type Limiter struct {
+	Store            Store
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	Window           time.Duration
+}
+
+func (l *Limiter) expired(rec Record, now time.Time) bool {
+	return now.Sub(rec.LastFailure) > l.Window && !rec.LockedUntil.After(now)
+}
+
+func (l *Limiter) delay(failures int) time.Duration {
+	if failures <= l.FreeAttempts {
+		return 0
+	}
+	d := l.BaseDelay
+	for i := l.FreeAttempts + 1; i < failures && d < l.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, l.MaxDelay)
+}
+
+// RetryAfter returns how long the caller has to wait before key may try
+// again, or 0 if it may try now.
+func (l *Limiter) RetryAfter(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	rec, err := l.Store.Get(ctx, key)
+	if err != nil || rec.Failures == 0 || l.expired(rec, now) {
+		return 0, err
+	}
+	if rec.LockedUntil.After(now) {
+		return rec.LockedUntil.Sub(now), nil
+	}
+	if next := rec.LastFailure.Add(l.delay(rec.Failures)); next.After(now) {
+		return next.Sub(now), nil
+	}
+	return 0, nil
+}
+
+// Fail records a failed attempt for key. locked reports whether this failure
+// started a new lockout.
+func (l *Limiter) Fail(ctx context.Context, key string, now time.Time) (rec Record, locked bool, err error) {
+	rec, err = l.Store.Increment(ctx, key, now, now.Add(-l.Window))
+	if err != nil {
+		return rec, false, err
+	}
+	if l.LockoutThreshold > 0 && rec.Failures >= l.LockoutThreshold && !rec.LockedUntil.After(now) {
+		until := now.Add(l.LockoutDuration)
+		locked, err = l.Store.Lock(ctx, key, until, now)
+		if locked {
+			rec.LockedUntil = until
+		}
+	}
+	return rec, locked, err
+}
+
+// Reset forgets every failure of key, e.g. after a successful login.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.Store.Delete(ctx, key)
+}
+
+// MemoryStore keeps records in process memory. It is only correct when a
+// single server instance handles every login.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+	ttl     time.Duration
+	sweepAt int
+}
+
+// NewMemoryStore returns a MemoryStore that drops records ttl after their
+// last failure once their lockout is over.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}, ttl: ttl, sweepAt: 1024}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[key], nil
+}
+
+func (s *MemoryStore) Increment(ctx context.Context, key string, now, expiredBefore time.Time) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec := s.records[key]
+	if rec.LastFailure.Before(expiredBefore) && !rec.LockedUntil.After(now) {
+		rec = Record{}
+	}
+	rec.Failures++
+	rec.LastFailure = now
+	s.records[key] = rec
+	s.sweep()
+	return rec, nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, key string, until, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[key]
+	if !ok || rec.LockedUntil.After(now) {
+		return false, nil
+	}
+	rec.LockedUntil = until
+	s.records[key] = rec
+	return true, nil
+}
+
+// sweep drops expired records once the map has doubled in size since the
+// last sweep. s.mu must be held.
+func (s *MemoryStore) sweep() {
+	if len(s.records) >= s.sweepAt {
+		now := time.Now()
+		for k, r := range s.records {
+			if now.Sub(r.LastFailure) > s.ttl && !r.LockedUntil.After(now) {
+				delete(s.records, k)
+			}
+		}
+		s.sweepAt = max(1024, 2*len(s.records))
+	}
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
@@ -0,0 +1,107 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newLimiter() *Limiter {
+	return &Limiter{
+		Store:            NewMemoryStore(time.Hour),
+		FreeAttempts:     2,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Second * 4,
+		LockoutThreshold: 6,
+		LockoutDuration:  time.Minute * 15,
+		Window:           time.Hour,
+	}
+}
+
+func TestLimiterBackoff(t *testing.T) {
+	ctx := context.Background()
+	l := newLimiter()
+	now := time.Unix(1700000000, 0)
+	want := []time.Duration{0, 0, time.Second, time.Second * 2, time.Second * 4}
+	for i, w := range want {
+		if _, locked, err := l.Fail(ctx, "a", now); err != nil || locked {
+			t.Fatalf("failure %d: locked=%v err=%v", i+1, locked, err)
+		}
+		got, _ := l.RetryAfter(ctx, "a", now)
+		if got != w {
+			t.Errorf("after %d failures: got wait %s, want %s", i+1, got, w)
+		}
+	}
+	if got, _ := l.RetryAfter(ctx, "a", now.Add(time.Second*4)); got != 0 {
+		t.Errorf("wait should be over, got %s", got)
+	}
+	if got, _ := l.RetryAfter(ctx, "b", now); got != 0 {
+		t.Errorf("keys should be independent, got %s", got)
+	}
+}
+
+func TestLimiterLockout(t *testing.T) {
+	ctx := context.Background()
+	l := newLimiter()
+	now := time.Unix(1700000000, 0)
+	var locked bool
+	for range 6 {
+		_, locked, _ = l.Fail(ctx, "a", now)
+	}
+	if !locked {
+		t.Fatal("expected the sixth failure to lock the key")
+	}
+	if got, _ := l.RetryAfter(ctx, "a", now.Add(time.Minute)); got != time.Minute*14 {
+		t.Errorf("got wait %s, want 14m", got)
+	}
+	if _, locked, _ = l.Fail(ctx, "a", now.Add(time.Minute)); locked {
+		t.Error("a failure during a lockout should not start a new one")
+	}
+
+	if err := l.Reset(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := l.RetryAfter(ctx, "a", now.Add(time.Minute)); got != 0 {
+		t.Errorf("reset key should not wait, got %s", got)
+	}
+}
+
+func TestLimiterWindow(t *testing.T) {
+	ctx := context.Background()
+	l := newLimiter()
+	now := time.Unix(1700000000, 0)
+	for range 4 {
+		l.Fail(ctx, "a", now)
+	}
+	rec, _, _ := l.Fail(ctx, "a", now.Add(time.Hour*2))
+	if rec.Failures != 1 {
+		t.Errorf("old failures should be forgotten, got %d", rec.Failures)
+	}
+}
+
+func TestLimiterParallelFailures(t *testing.T) {
+	ctx := context.Background()
+	l := newLimiter()
+	now := time.Unix(1700000000, 0)
+	var wg sync.WaitGroup
+	var lockouts atomic.Int32
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, locked, _ := l.Fail(ctx, "a", now); locked {
+				lockouts.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	rec, _ := l.Store.Get(ctx, "a")
+	if rec.Failures != 50 {
+		t.Errorf("parallel failures should all count, got %d", rec.Failures)
+	}
+	if n := lockouts.Load(); n != 1 {
+		t.Errorf("expected exactly one lockout, got %d", n)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"encoding/json"
+	"http_server/internal/auth"
+	"net/http"
+)
+
+// jwks publishes the public keys access tokens are signed with, so other
+// services can verify them without holding any secret.
+func (c *apiConfig) jwks(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Keys []auth.JWK `json:"keys"`
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&responseStruct{Keys: c.keys.JWKS()})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"http_server/internal/auth"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// chirpResponse is a chirp as returned to clients, together with the public
+// profile of its author, its images and its engagement counters.
+type chirpResponse struct {
+	database.Chirp
+	Author       profile         `json:"author"`
+	Media        []mediaResponse `json:"media"`
+	LikeCount    int64           `json:"like_count"`
+	RechirpCount int64           `json:"rechirp_count"`
+	LikedByMe    bool            `json:"liked_by_me"`
+}
+
+// viewerID returns the caller if the request carries a valid access token of
+// a user who is not suspended.
+// Endpoints using it also serve anonymous requests.
+func (c *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	tokenString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userID, err := auth.ValidateJWT(tokenString, c.keys)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	if standing, err := c.queries.GetUserStanding(r.Context(), userID); err != nil || suspended(standing) {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+func (c *apiConfig) chirpResponses(ctx context.Context, chirps []database.Chirp, viewer uuid.NullUUID) ([]chirpResponse, error) {
+	res := make([]chirpResponse, 0, len(chirps))
+	if len(chirps) == 0 {
+		return res, nil
+	}
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	rows, err := c.queries.GetChirpEngagement(ctx, database.GetChirpEngagementParams{
+		ViewerID: viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	engagement := make(map[uuid.UUID]database.GetChirpEngagementRow, len(rows))
+	for _, row := range rows {
+		engagement[row.ID] = row
+	}
+	authors, err := c.authorProfiles(ctx, chirps)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := c.chirpMedia(ctx, chirps)
+	if err != nil {
+		return nil, err
+	}
+	for _, chirp := range chirps {
+		e := engagement[chirp.ID]
+		m := attachments[chirp.ID]
+		if m == nil {
+			m = []mediaResponse{}
+		}
+		res = append(res, chirpResponse{
+			Chirp:        shadowHide(chirp),
+			Author:       authors[chirp.UserID],
+			Media:        m,
+			LikeCount:    e.LikeCount,
+			RechirpCount: e.RechirpCount,
+			LikedByMe:    e.LikedByMe,
+		})
+	}
+	return res, nil
+}
+
+// reactToChirp makes sure the chirp exists and then runs the like or rechirp
+// query for it on behalf of the caller.
+func (c *apiConfig) reactToChirp(w http.ResponseWriter, r *http.Request, react func(ctx context.Context, userID, chirpID uuid.UUID) error) {
+	userID := callerID(r)
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		http.Error(w, "unable to parse chirpID", http.StatusNotFound)
+		return
+	}
+	chirp, err := c.queries.GetSingleChirp(r.Context(), chirpID)
+	if err != nil || !c.canSee(r.Context(), chirp, uuid.NullUUID{UUID: userID, Valid: true}) {
+		http.Error(w, "Can not find chirp", http.StatusNotFound)
+		return
+	}
+	if err := react(r.Context(), userID, chirpID); err != nil {
+		http.Error(w, "Interal database error", http.StatusInternalServerError)
+		log.Printf("Error reacting to chirp %s: %s", chirpID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	c.reactToChirp(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return c.queries.LikeChirp(ctx, database.LikeChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+func (c *apiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	c.reactToChirp(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return c.queries.UnlikeChirp(ctx, database.UnlikeChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+func (c *apiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	c.reactToChirp(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return c.queries.Rechirp(ctx, database.RechirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+func (c *apiConfig) undoRechirp(w http.ResponseWriter, r *http.Request) {
+	c.reactToChirp(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return c.queries.UndoRechirp(ctx, database.UndoRechirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
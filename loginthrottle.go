@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"http_server/internal/database"
+	"http_server/internal/throttle"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Failed logins are throttled per email address and per client IP. The IP
+// limits are looser since many users can share one address.
+func newLoginLimiters(store throttle.Store) (email, ip *throttle.Limiter) {
+	email = &throttle.Limiter{
+		Store:            store,
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  time.Minute * 15,
+		Window:           time.Hour,
+	}
+	ip = &throttle.Limiter{
+		Store:            store,
+		FreeAttempts:     20,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 100,
+		LockoutDuration:  time.Hour,
+		Window:           time.Hour,
+	}
+	return email, ip
+}
+
+// loginThrottleStore keeps throttle records in Postgres so every server
+// instance sees the same failures.
+type loginThrottleStore struct {
+	queries *database.Queries
+}
+
+func (s loginThrottleStore) Get(ctx context.Context, key string) (throttle.Record, error) {
+	row, err := s.queries.GetLoginAttempt(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return throttle.Record{}, nil
+	}
+	if err != nil {
+		return throttle.Record{}, err
+	}
+	return throttle.Record{
+		Failures:    int(row.Failures),
+		LastFailure: row.LastFailureAt,
+		LockedUntil: row.LockedUntil.Time,
+	}, nil
+}
+
+func (s loginThrottleStore) Increment(ctx context.Context, key string, now, expiredBefore time.Time) (throttle.Record, error) {
+	row, err := s.queries.IncrementLoginAttempt(ctx, database.IncrementLoginAttemptParams{
+		ThrottleKey:   key,
+		LastFailureAt: now,
+		ExpiredBefore: expiredBefore,
+	})
+	if err != nil {
+		return throttle.Record{}, err
+	}
+	return throttle.Record{
+		Failures:    int(row.Failures),
+		LastFailure: row.LastFailureAt,
+		LockedUntil: row.LockedUntil.Time,
+	}, nil
+}
+
+func (s loginThrottleStore) Lock(ctx context.Context, key string, until, now time.Time) (bool, error) {
+	n, err := s.queries.LockLoginAttempt(ctx, database.LockLoginAttemptParams{
+		LockedUntil: sql.NullTime{Time: until, Valid: true},
+		ThrottleKey: key,
+		Now:         now,
+	})
+	return n > 0, err
+}
+
+func (s loginThrottleStore) Delete(ctx context.Context, key string) error {
+	return s.queries.DeleteLoginAttempt(ctx, key)
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginRetryAfter returns how long a login for email from r has to wait. The
+// check fails open so a broken store does not lock everybody out.
+func (c *apiConfig) loginRetryAfter(r *http.Request, email string) time.Duration {
+	now := time.Now()
+	wait, err := c.emailLimiter.RetryAfter(r.Context(), emailThrottleKey(email), now)
+	if err != nil {
+		log.Printf("Error checking login throttle: %s", err)
+	}
+	ipWait, err := c.ipLimiter.RetryAfter(r.Context(), ipThrottleKey(clientIP(r)), now)
+	if err != nil {
+		log.Printf("Error checking login throttle: %s", err)
+	}
+	return max(wait, ipWait)
+}
+
+// loginFailed records a failed login and a lockout event for every limit it
+// pushes over the lockout threshold.
+func (c *apiConfig) loginFailed(r *http.Request, email string, userID uuid.NullUUID) {
+	now := time.Now()
+	ip := clientIP(r)
+	for key, limiter := range map[string]*throttle.Limiter{
+		emailThrottleKey(email): c.emailLimiter,
+		ipThrottleKey(ip):       c.ipLimiter,
+	} {
+		rec, locked, err := limiter.Fail(r.Context(), key, now)
+		if err != nil {
+			log.Printf("Error recording failed login: %s", err)
+			continue
+		}
+		if !locked {
+			continue
+		}
+		log.Printf("Locked out %s until %s after %d failed logins", key, rec.LockedUntil.Format(time.RFC3339), rec.Failures)
+		err = c.queries.InsertLockoutEvent(r.Context(), database.InsertLockoutEventParams{
+			ThrottleKey: key,
+			UserID:      userID,
+			IpAddress:   ip,
+			Failures:    int32(min(rec.Failures, math.MaxInt32)),
+			LockedUntil: rec.LockedUntil,
+		})
+		if err != nil {
+			log.Printf("Error recording lockout event: %s", err)
+		}
+	}
+}
+
+func (c *apiConfig) loginSucceeded(r *http.Request, email string) {
+	if err := c.emailLimiter.Reset(r.Context(), emailThrottleKey(email)); err != nil {
+		log.Printf("Error resetting login throttle: %s", err)
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many failed login attempts", http.StatusTooManyRequests)
+}
+
+// listLockoutEvents shows the most recent lockouts, newest first.
+func (c *apiConfig) listLockoutEvents(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Lockouts []database.LockoutEvent `json:"lockouts"`
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := c.queries.ListLockoutEvents(r.Context(), limit)
+	if err != nil {
+		http.Error(w, "Could not retrieve lockouts", http.StatusInternalServerError)
+		log.Printf("Error listing lockout events: %s", err)
+		return
+	}
+	res := responseStruct{Lockouts: events}
+	if res.Lockouts == nil {
+		res.Lockouts = []database.LockoutEvent{}
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&res)
+}
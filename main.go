@@ -1,11 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"http_server/internal/auth"
 	"http_server/internal/database"
+	"http_server/internal/mail"
+	"http_server/internal/media"
+	"http_server/internal/moderation"
+	"http_server/internal/throttle"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -20,23 +29,139 @@ func main() {
 	if err != nil {
 		log.Fatalf("Can not connect to database: %s", err)
 	}
+	cfg.db = db
 	cfg.queries = *database.New(db)
-	cfg.secret = os.Getenv("SECRET")
+	cfg.keys, err = auth.KeyringFromEnv(os.Getenv("JWT_KEYS"), os.Getenv("SECRET"))
+	if err != nil {
+		log.Fatalf("Can not load JWT signing keys: %s", err)
+	}
+	cfg.hasher, err = auth.ParseHasher(os.Getenv("PASSWORD_HASHER"))
+	if err != nil {
+		log.Fatalf("Invalid password hasher: %s", err)
+	}
+	cfg.passwordPolicy = auth.PolicyFor(cfg.hasher)
+	if path := os.Getenv("BREACHED_PASSWORDS"); path != "" {
+		cfg.passwordPolicy.Breached, err = auth.LoadBreachedList(path)
+		if err != nil {
+			log.Fatalf("Can not load breached password list: %s", err)
+		}
+	}
+	// Blobs are served by serveMedia with their own headers; below "." they
+	// would also be reachable through /app/ without them.
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = filepath.Join(os.TempDir(), "chirpy-media")
+	}
+	if insideDir(mediaDir, ".") {
+		log.Fatalf("MEDIA_DIR %q is inside the directory served under /app/", mediaDir)
+	}
+	cfg.blobs = &media.DiskStore{Dir: mediaDir}
+	cfg.imageJobs = make(chan struct{}, MAXIMAGEJOBS)
+	cfg.moderator, err = moderation.New(context.Background(),
+		moderationSource(&cfg.queries, os.Getenv("MODERATION_WORDS")),
+		moderationStore{queries: &cfg.queries},
+	)
+	if err != nil {
+		log.Fatalf("Can not load moderation word list: %s", err)
+	}
+	go cfg.moderator.Watch(context.Background(), time.Minute)
 	cfg.polka_key = os.Getenv("POLKA_KEY")
+	// /app/ serves the working directory, so mail with live tokens must
+	// not be written anywhere below it.
+	mailDir := os.Getenv("MAIL_DIR")
+	if mailDir != "" && insideDir(mailDir, ".") {
+		log.Fatalf("MAIL_DIR %q is inside the directory served under /app/", mailDir)
+	}
+	cfg.mailer = mail.FromEnv(
+		os.Getenv("MAIL_SMTP_ADDR"),
+		os.Getenv("MAIL_SMTP_USER"),
+		os.Getenv("MAIL_SMTP_PASSWORD"),
+		os.Getenv("MAIL_FROM"),
+		mailDir,
+	)
+	cfg.appURL = os.Getenv("APP_URL")
+	cfg.adminEmails = parseAdminEmails(os.Getenv("ADMIN_EMAILS"))
+	var throttleStore throttle.Store = loginThrottleStore{queries: &cfg.queries}
+	if os.Getenv("LOGIN_THROTTLE_STORE") == "memory" {
+		throttleStore = throttle.NewMemoryStore(time.Hour)
+	}
+	cfg.emailLimiter, cfg.ipLimiter = newLoginLimiters(throttleStore)
+	cfg.resetEmailLimiter, cfg.resetIPLimiter = newResetLimiters(throttleStore)
+	cfg.startPasswordResetWorkers()
+	go cfg.purgeDeletedAccounts(context.Background(), time.Hour)
+	go cfg.sweepStaleMedia(context.Background(), time.Hour)
+
 	mux := http.NewServeMux()
 	mux.Handle("/app/", cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
-	mux.Handle("GET /admin/metrics", cfg.metrics())
+	mux.Handle("GET /admin/metrics", cfg.authorize(cfg.metrics(), auth.ScopeAdmin))
 	mux.Handle("GET /api/chirps", http.HandlerFunc(cfg.getAllChirps))
 	mux.Handle("GET /api/chirps/{chirpID}", cfg.getSingleChirp())
+	mux.Handle("GET /api/chirps/search", http.HandlerFunc(cfg.searchChirps))
+	mux.Handle("GET /api/chirps/{chirpID}/revisions", http.HandlerFunc(cfg.getChirpRevisions))
+	mux.Handle("GET /api/chirps/{chirpID}/thread", http.HandlerFunc(cfg.getChirpThread))
+	mux.Handle("GET /media/{key}", http.HandlerFunc(cfg.serveMedia))
 	mux.HandleFunc("GET /api/healthz", healthz)
-	mux.Handle("POST /admin/reset", cfg.reset())
+	mux.Handle("GET /.well-known/jwks.json", http.HandlerFunc(cfg.jwks))
+	reset := cfg.authorize(cfg.reset(), auth.ScopeAdmin)
+	if cfg.Platform == "dev" {
+		// Test runs reset the database before any admin exists.
+		reset = cfg.reset()
+	}
+	mux.Handle("POST /admin/reset", reset)
+	mux.Handle("GET /admin/lockouts", cfg.authorize(http.HandlerFunc(cfg.listLockoutEvents), auth.ScopeAdmin))
+	mux.Handle("GET /admin/moderation/words", cfg.authorize(http.HandlerFunc(cfg.listModerationWords), auth.ScopeAdmin))
+	mux.Handle("POST /admin/moderation/words", cfg.authorize(http.HandlerFunc(cfg.addModerationWord), auth.ScopeAdmin))
+	mux.Handle("DELETE /admin/moderation/words/{word}", cfg.authorize(http.HandlerFunc(cfg.deleteModerationWord), auth.ScopeAdmin))
+	mux.Handle("GET /admin/moderation/rules", cfg.authorize(http.HandlerFunc(cfg.listModerationRules), auth.ScopeAdmin))
+	mux.Handle("POST /admin/moderation/rules", cfg.authorize(http.HandlerFunc(cfg.createModerationRule), auth.ScopeAdmin))
+	mux.Handle("DELETE /admin/moderation/rules/{ruleID}", cfg.authorize(http.HandlerFunc(cfg.deleteModerationRule), auth.ScopeAdmin))
+	mux.Handle("GET /admin/moderation/rules/{ruleID}/hits", cfg.authorize(http.HandlerFunc(cfg.listModerationRuleHits), auth.ScopeAdmin))
+	mux.Handle("GET /admin/moderation/held", cfg.authorize(http.HandlerFunc(cfg.listHeldChirps), auth.ScopeAdmin))
+	mux.Handle("GET /admin/reports", cfg.authorize(http.HandlerFunc(cfg.listOpenReports), auth.ScopeAdmin))
+	mux.Handle("GET /admin/reports/{reportID}", cfg.authorize(http.HandlerFunc(cfg.getReport), auth.ScopeAdmin))
+	mux.Handle("POST /admin/reports/{reportID}/resolve", cfg.authorize(http.HandlerFunc(cfg.resolveReport), auth.ScopeAdmin))
+	mux.Handle("PUT /admin/chirps/{chirpID}/moderation", cfg.authorize(http.HandlerFunc(cfg.setChirpModerationStatus), auth.ScopeAdmin))
+	mux.Handle("POST /admin/moderation/reload", cfg.authorize(http.HandlerFunc(cfg.reloadModerationWords), auth.ScopeAdmin))
+	mux.Handle("PUT /admin/users/{userID}/roles", cfg.authorize(http.HandlerFunc(cfg.setUserRoles), auth.ScopeAdmin))
+	mux.Handle("POST /admin/users/{userID}/suspend", cfg.authorize(http.HandlerFunc(cfg.suspendUser), auth.ScopeAdmin))
+	mux.Handle("POST /admin/users/{userID}/ban", cfg.authorize(http.HandlerFunc(cfg.banUser), auth.ScopeAdmin))
+	mux.Handle("DELETE /admin/users/{userID}/suspension", cfg.authorize(http.HandlerFunc(cfg.liftUserSuspension), auth.ScopeAdmin))
 	mux.Handle("POST /api/users", cfg.createUser())
-	mux.Handle("POST /api/chirps", cfg.postChirp())
+	mux.Handle("POST /api/chirps", cfg.authorize(cfg.postChirp(), auth.ScopeChirpsWrite))
+	mux.Handle("POST /api/media", cfg.authorize(http.HandlerFunc(cfg.uploadMedia), auth.ScopeChirpsWrite))
+	mux.Handle("PUT /api/users/avatar", cfg.authorize(http.HandlerFunc(cfg.uploadAvatar), auth.ScopeUsersWrite))
 	mux.Handle("POST /api/login", cfg.login())
+	mux.Handle("POST /api/login/2fa", http.HandlerFunc(cfg.loginTwoFactor))
+	mux.Handle("POST /api/users/2fa/totp", cfg.authorize(http.HandlerFunc(cfg.enrollTOTP), auth.ScopeUsersWrite))
+	mux.Handle("POST /api/users/2fa/totp/verify", cfg.authorize(http.HandlerFunc(cfg.verifyTOTP), auth.ScopeUsersWrite))
+	mux.Handle("DELETE /api/users/2fa/totp", cfg.authorize(http.HandlerFunc(cfg.disableTOTP), auth.ScopeUsersWrite))
+	mux.Handle("GET /api/verify-email", http.HandlerFunc(cfg.verifyEmail))
+	mux.Handle("POST /api/password-reset", http.HandlerFunc(cfg.requestPasswordReset))
+	mux.Handle("POST /api/password-reset/confirm", http.HandlerFunc(cfg.confirmPasswordReset))
 	mux.Handle("POST /api/refresh", http.HandlerFunc(cfg.refreshToken))
 	mux.Handle("POST /api/revoke", http.HandlerFunc(cfg.revokeRefreshToken))
-	mux.Handle("PUT /api/users", http.HandlerFunc(cfg.updateUserEmailPassword))
-	mux.Handle("DELETE /api/chirps/{chirpID}", http.HandlerFunc(cfg.deleteChirp))
+	mux.Handle("GET /api/sessions", cfg.authorize(http.HandlerFunc(cfg.listSessions)))
+	mux.Handle("DELETE /api/sessions/{id}", cfg.authorize(http.HandlerFunc(cfg.deleteSession), auth.ScopeUsersWrite))
+	mux.Handle("POST /api/logout-all", cfg.authorize(http.HandlerFunc(cfg.logoutAll), auth.ScopeUsersWrite))
+	mux.Handle("PATCH /api/users", cfg.authorize(http.HandlerFunc(cfg.patchUser), auth.ScopeUsersWrite))
+	mux.Handle("DELETE /api/users", cfg.authorize(http.HandlerFunc(cfg.deleteAccount), auth.ScopeUsersWrite))
+	mux.Handle("GET /api/users/export", cfg.authorize(http.HandlerFunc(cfg.exportAccount)))
+	mux.Handle("PUT /api/users", cfg.authorize(http.HandlerFunc(cfg.updateUserEmailPassword), auth.ScopeUsersWrite))
+	mux.Handle("PUT /api/chirps/{chirpID}", cfg.authorize(http.HandlerFunc(cfg.editChirp), auth.ScopeChirpsWrite))
+	mux.Handle("DELETE /api/chirps/{chirpID}", cfg.authorize(http.HandlerFunc(cfg.deleteChirp), auth.ScopeChirpsWrite))
+	mux.Handle("POST /api/chirps/{chirpID}/like", cfg.authorize(http.HandlerFunc(cfg.likeChirp), auth.ScopeChirpsWrite))
+	mux.Handle("DELETE /api/chirps/{chirpID}/like", cfg.authorize(http.HandlerFunc(cfg.unlikeChirp), auth.ScopeChirpsWrite))
+	mux.Handle("POST /api/chirps/{chirpID}/rechirp", cfg.authorize(http.HandlerFunc(cfg.rechirp), auth.ScopeChirpsWrite))
+	mux.Handle("POST /api/chirps/{chirpID}/report", cfg.authorize(http.HandlerFunc(cfg.reportChirp), auth.ScopeChirpsWrite))
+	mux.Handle("DELETE /api/chirps/{chirpID}/rechirp", cfg.authorize(http.HandlerFunc(cfg.undoRechirp), auth.ScopeChirpsWrite))
+	mux.Handle("POST /api/users/{userID}/follow", cfg.authorize(http.HandlerFunc(cfg.followUser), auth.ScopeUsersWrite))
+	mux.Handle("DELETE /api/users/{userID}/follow", cfg.authorize(http.HandlerFunc(cfg.unfollowUser), auth.ScopeUsersWrite))
+	mux.Handle("POST /api/users/{userID}/block", cfg.authorize(http.HandlerFunc(cfg.blockUser), auth.ScopeUsersWrite))
+	mux.Handle("DELETE /api/users/{userID}/block", cfg.authorize(http.HandlerFunc(cfg.unblockUser), auth.ScopeUsersWrite))
+	mux.Handle("POST /api/users/{userID}/mute", cfg.authorize(http.HandlerFunc(cfg.muteUser), auth.ScopeUsersWrite))
+	mux.Handle("DELETE /api/users/{userID}/mute", cfg.authorize(http.HandlerFunc(cfg.unmuteUser), auth.ScopeUsersWrite))
+	mux.Handle("GET /api/users/{handle}", http.HandlerFunc(cfg.getUserProfile))
+	mux.Handle("GET /api/timeline", cfg.authorize(http.HandlerFunc(cfg.getTimeline)))
 	mux.Handle("POST /api/polka/webhooks", http.HandlerFunc(cfg.upgradeUser))
 
 	httpserver := http.Server{
@@ -45,3 +170,18 @@ func main() {
 	}
 	httpserver.ListenAndServe()
 }
+
+// insideDir reports whether path is dir or lies below it. Paths that can not
+// be resolved count as inside.
+func insideDir(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return true
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return true
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err != nil || !(rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"http_server/internal/database"
+	"http_server/internal/media"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MAXUPLOADSIZE int64 = 5 << 20
+	MAXCHIRPMEDIA int   = 4
+	// MAXIMAGEJOBS uploads are decoded at a time, since each one can take
+	// about media.MaxPixels*4 bytes of memory.
+	MAXIMAGEJOBS int = 4
+	// UNATTACHEDMEDIATTL is how long an upload may wait to be attached to
+	// a chirp before sweepStaleMedia removes it.
+	UNATTACHEDMEDIATTL time.Duration = time.Hour * 24
+)
+
+// What an upload is for, as stored in media.kind. Only chirp uploads can be
+// attached to chirps.
+const (
+	MEDIACHIRP  = "chirp"
+	MEDIAAVATAR = "avatar"
+)
+
+// Errors from attaching media to a new chirp.
+var (
+	errUnknownMedia = errors.New("unknown or already used media")
+	errMediaTaken   = errors.New("media attached to another chirp")
+)
+
+type mediaResponse struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+}
+
+func (c *apiConfig) mediaURL(key string) string {
+	return c.appURL + "/media/" + key
+}
+
+func (c *apiConfig) mediaResponseOf(m database.Medium) mediaResponse {
+	return mediaResponse{
+		ID:           m.ID,
+		URL:          c.mediaURL(m.BlobKey),
+		ThumbnailURL: c.mediaURL(m.ThumbnailKey),
+		ContentType:  m.ContentType,
+		Width:        m.Width,
+		Height:       m.Height,
+	}
+}
+
+// chirpMedia loads the images attached to chirps, keyed by chirp ID.
+func (c *apiConfig) chirpMedia(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID][]mediaResponse, error) {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	rows, err := c.queries.ListChirpMedia(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[uuid.UUID][]mediaResponse, len(chirps))
+	for _, row := range rows {
+		res[row.ChirpID.UUID] = append(res[row.ChirpID.UUID], c.mediaResponseOf(row))
+	}
+	return res, nil
+}
+
+// processUpload reads the "file" part of a multipart upload and turns it into
+// a stored image of the given kind owned by the caller.
+func (c *apiConfig) processUpload(w http.ResponseWriter, r *http.Request, opts media.Options, kind string) (database.Medium, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, MAXUPLOADSIZE+(1<<20))
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Expected an image in the multipart field \"file\"", http.StatusBadRequest)
+		return database.Medium{}, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, MAXUPLOADSIZE+1))
+	if err != nil {
+		http.Error(w, "Could not read upload", http.StatusBadRequest)
+		return database.Medium{}, false
+	}
+	if int64(len(data)) > MAXUPLOADSIZE {
+		http.Error(w, "Image is too large", http.StatusRequestEntityTooLarge)
+		return database.Medium{}, false
+	}
+	select {
+	case c.imageJobs <- struct{}{}:
+	case <-r.Context().Done():
+		return database.Medium{}, false
+	}
+	img, err := media.Process(data, opts)
+	<-c.imageJobs
+	switch {
+	case errors.Is(err, media.ErrUnsupportedType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return database.Medium{}, false
+	case errors.Is(err, media.ErrTooManyPixels):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return database.Medium{}, false
+	case err != nil:
+		http.Error(w, "Could not process image", http.StatusUnprocessableEntity)
+		return database.Medium{}, false
+	}
+
+	id := uuid.New()
+	ext := ".png"
+	if img.Full.ContentType == "image/jpeg" {
+		ext = ".jpg"
+	}
+	blobKey, thumbKey := id.String()+ext, id.String()+"_thumb"+ext
+	if err := c.blobs.Put(r.Context(), blobKey, img.Full.Data); err != nil {
+		http.Error(w, "Could not store image", http.StatusInternalServerError)
+		log.Printf("Error storing blob %s: %s", blobKey, err)
+		return database.Medium{}, false
+	}
+	if err := c.blobs.Put(r.Context(), thumbKey, img.Thumbnail.Data); err != nil {
+		http.Error(w, "Could not store image", http.StatusInternalServerError)
+		log.Printf("Error storing blob %s: %s", thumbKey, err)
+		c.deleteBlobs(r.Context(), blobKey)
+		return database.Medium{}, false
+	}
+	m, err := c.queries.InsertMedia(r.Context(), database.InsertMediaParams{
+		ID:           id,
+		UserID:       callerID(r),
+		ContentType:  img.Full.ContentType,
+		Width:        int32(img.Full.Width),
+		Height:       int32(img.Full.Height),
+		BlobKey:      blobKey,
+		ThumbnailKey: thumbKey,
+		Kind:         kind,
+	})
+	if err != nil {
+		http.Error(w, "Interal database error", http.StatusInternalServerError)
+		log.Printf("Error inserting media %s: %s", id, err)
+		c.deleteBlobs(r.Context(), blobKey, thumbKey)
+		return database.Medium{}, false
+	}
+	return m, true
+}
+
+// uploadMedia stores an image that can be attached to the caller's next
+// chirp through its media_ids.
+func (c *apiConfig) uploadMedia(w http.ResponseWriter, r *http.Request) {
+	m, ok := c.processUpload(w, r, media.ChirpImage, MEDIACHIRP)
+	if !ok {
+		return
+	}
+	res := c.mediaResponseOf(m)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&res)
+}
+
+// uploadAvatar replaces the caller's avatar. The previous one is deleted in
+// the same transaction; an upload that loses a race with another one is
+// left unreferenced for sweepStaleMedia.
+func (c *apiConfig) uploadAvatar(w http.ResponseWriter, r *http.Request) {
+	m, ok := c.processUpload(w, r, media.Avatar, MEDIAAVATAR)
+	if !ok {
+		return
+	}
+	var user database.User
+	var replaced []database.DeleteAvatarMediaRow
+	err := c.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		replaced, err = q.DeleteAvatarMedia(r.Context(), m.UserID)
+		if err != nil {
+			return err
+		}
+		user, err = q.UpdateUserFields(r.Context(), database.UpdateUserFieldsParams{
+			ID:        m.UserID,
+			AvatarUrl: sql.NullString{String: c.mediaURL(m.BlobKey), Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error setting avatar of %s: %s", m.UserID, err)
+		return
+	}
+	for _, f := range replaced {
+		c.deleteBlobs(r.Context(), f.BlobKey, f.ThumbnailKey)
+	}
+	res := profileOf(user)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&res)
+}
+
+// deleteChirpWithMedia deletes a chirp along with its media rows and returns
+// the blob keys to remove once the caller's transaction has committed.
+func deleteChirpWithMedia(ctx context.Context, q *database.Queries, chirpID uuid.UUID) ([]string, error) {
+	files, err := q.DeleteChirpMedia(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := q.DeleteSingleChirp(ctx, chirpID); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, 2*len(files))
+	for _, f := range files {
+		keys = append(keys, f.BlobKey, f.ThumbnailKey)
+	}
+	return keys, nil
+}
+
+// deleteBlobs removes files nothing refers to any more. It keeps going when
+// the request that triggered it is cancelled, and only logs failures: a
+// leftover file is harmless, a half-finished request is not.
+func (c *apiConfig) deleteBlobs(ctx context.Context, keys ...string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, key := range keys {
+		if err := c.blobs.Delete(ctx, key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Error deleting blob %s: %s", key, err)
+		}
+	}
+}
+
+// sweepStaleMedia removes uploads that were never attached to a chirp and
+// are not anyone's avatar every interval until ctx is done.
+func (c *apiConfig) sweepStaleMedia(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		files, err := c.queries.DeleteStaleMedia(ctx, time.Now().Add(-UNATTACHEDMEDIATTL))
+		if err != nil {
+			log.Printf("Error deleting unattached media: %s", err)
+		} else if len(files) > 0 {
+			log.Printf("Deleted %d unattached uploads", len(files))
+		}
+		for _, f := range files {
+			c.deleteBlobs(ctx, f.BlobKey, f.ThumbnailKey)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// serveMedia serves uploaded images from the blob store. Keys contain a
+// random UUID and are never reused, so they can be cached forever.
+func (c *apiConfig) serveMedia(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	f, err := c.blobs.Open(r.Context(), key)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, media.ErrInvalidKey) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not read media", http.StatusInternalServerError)
+		log.Printf("Error opening blob %s: %s", key, err)
+		return
+	}
+	defer f.Close()
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set(CONTENTTYPE, contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"http_server/internal/database"
+	"http_server/internal/moderation"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// MAXMODERATIONWORDLENGTH keeps admins from adding whole sentences, which
+// could never match since the filter compares single words.
+const MAXMODERATIONWORDLENGTH int = 64
+
+// Moderation statuses of a chirp. Only visible chirps are shown to anyone but
+// their author.
+const (
+	CHIRPVISIBLE = "visible"
+	CHIRPPENDING = "pending"
+	CHIRPHIDDEN  = "hidden"
+)
+
+// chirpStatusOrder ranks statuses so an edit can never lift a chirp out of
+// review or out of hiding.
+var chirpStatusOrder = map[string]int{
+	CHIRPVISIBLE: 0,
+	CHIRPPENDING: 1,
+	CHIRPHIDDEN:  2,
+}
+
+// moderationStore loads the words and rules admins added through the API.
+type moderationStore struct {
+	queries *database.Queries
+}
+
+func (s moderationStore) Load(ctx context.Context) ([]string, error) {
+	rows, err := s.queries.ListModerationWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]string, 0, len(rows))
+	for _, row := range rows {
+		words = append(words, row.Word)
+	}
+	return words, nil
+}
+
+func (s moderationStore) Rules(ctx context.Context) ([]moderation.Rule, error) {
+	rows, err := s.queries.ListModerationRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]moderation.Rule, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, moderationRuleOf(row))
+	}
+	return rules, nil
+}
+
+func moderationRuleOf(row database.ModerationRule) moderation.Rule {
+	return moderation.Rule{
+		ID:      row.ID,
+		Kind:    moderation.Kind(row.Kind),
+		Pattern: row.Pattern,
+		Action:  moderation.Action(row.Action),
+		Reason:  row.Reason,
+	}
+}
+
+// moderationSource is the base list, from path if set and the built-in
+// defaults otherwise, plus the words stored in the database.
+func moderationSource(queries *database.Queries, path string) moderation.Source {
+	var base moderation.Source = moderation.DefaultWords
+	if path != "" {
+		base = moderation.FileSource{Path: path}
+	}
+	return moderation.Sources{base, moderationStore{queries: queries}}
+}
+
+// reloadModeration applies a change to the word list right away instead of
+// waiting for the next periodic reload.
+func (c *apiConfig) reloadModeration(w http.ResponseWriter, r *http.Request) bool {
+	if err := c.moderator.Reload(r.Context()); err != nil {
+		http.Error(w, "Could not reload word list", http.StatusInternalServerError)
+		log.Printf("Error reloading moderation word list: %s", err)
+		return false
+	}
+	return true
+}
+
+func (c *apiConfig) listModerationWords(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Words  []database.ModerationWord `json:"words"`
+		Active int                       `json:"active_rules"`
+	}
+	words, err := c.queries.ListModerationWords(r.Context())
+	if err != nil {
+		http.Error(w, "Could not retrieve word list", http.StatusInternalServerError)
+		log.Printf("Error listing moderation words: %s", err)
+		return
+	}
+	res := responseStruct{Words: words, Active: c.moderator.Engine().Len()}
+	if res.Words == nil {
+		res.Words = []database.ModerationWord{}
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&res)
+}
+
+func (c *apiConfig) addModerationWord(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Word string `json:"word"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	word := moderation.Normalize(strings.TrimSpace(req.Word))
+	if word == "" || len(word) > MAXMODERATIONWORDLENGTH || strings.ContainsFunc(word, unicode.IsSpace) {
+		http.Error(w, "Expected a single word", http.StatusBadRequest)
+		return
+	}
+	if err := c.queries.AddModerationWord(r.Context(), word); err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error adding moderation word: %s", err)
+		return
+	}
+	if !c.reloadModeration(w, r) {
+		return
+	}
+	log.Printf("User %s added a moderation word", callerID(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) deleteModerationWord(w http.ResponseWriter, r *http.Request) {
+	word := moderation.Normalize(r.PathValue("word"))
+	deleted, err := c.queries.DeleteModerationWord(r.Context(), word)
+	if err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error deleting moderation word: %s", err)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Word not found", http.StatusNotFound)
+		return
+	}
+	if !c.reloadModeration(w, r) {
+		return
+	}
+	log.Printf("User %s removed a moderation word", callerID(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reloadModerationWords picks up edits to the word list file and rules
+// changed directly in the database.
+func (c *apiConfig) reloadModerationWords(w http.ResponseWriter, r *http.Request) {
+	if c.reloadModeration(w, r) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// visibleTo reports whether viewer may see chirp. Held and hidden chirps are
+// only shown to their author.
+func visibleTo(chirp database.Chirp, viewer uuid.NullUUID) bool {
+	return chirp.ModerationStatus == CHIRPVISIBLE || (viewer.Valid && viewer.UUID == chirp.UserID)
+}
+
+// shadowHide reports a hidden chirp as visible, so its author can not tell
+// it was hidden.
+func shadowHide(chirp database.Chirp) database.Chirp {
+	if chirp.ModerationStatus == CHIRPHIDDEN {
+		chirp.ModerationStatus = CHIRPVISIBLE
+	}
+	return chirp
+}
+
+// chirpStatus is the status a chirp gets for a moderation verdict.
+func chirpStatus(action moderation.Action) string {
+	switch action {
+	case moderation.ActionHold:
+		return CHIRPPENDING
+	case moderation.ActionHide:
+		return CHIRPHIDDEN
+	}
+	return CHIRPVISIBLE
+}
+
+// moderateChirp runs the moderation rules on a chirp body. A rejected chirp
+// gets a 400 with the rule's reason, and false is returned.
+func (c *apiConfig) moderateChirp(w http.ResponseWriter, r *http.Request, body string) (moderation.Verdict, bool) {
+	type responseStruct struct {
+		Error  string `json:"error"`
+		Reason string `json:"reason,omitempty"`
+	}
+	v := c.moderator.Evaluate(body)
+	if v.Action != moderation.ActionReject {
+		return v, true
+	}
+	c.recordModerationHits(r.Context(), callerID(r), uuid.NullUUID{}, v)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(&responseStruct{
+		Error:  "Chirp was rejected by moderation",
+		Reason: v.Reason,
+	})
+	return v, false
+}
+
+// recordModerationHits keeps an audit trail of which chirps each rule hit.
+// Words from the word list have no rule ID and are not recorded.
+func (c *apiConfig) recordModerationHits(ctx context.Context, userID uuid.UUID, chirpID uuid.NullUUID, v moderation.Verdict) {
+	for _, hit := range v.Hits {
+		if hit.Rule.ID == uuid.Nil {
+			continue
+		}
+		err := c.queries.InsertModerationHit(ctx, database.InsertModerationHitParams{
+			RuleID:  hit.Rule.ID,
+			UserID:  userID,
+			ChirpID: chirpID,
+			Action:  string(hit.Rule.Action),
+			Matched: hit.Match,
+		})
+		if err != nil {
+			log.Printf("Error recording hit of moderation rule %s: %s", hit.Rule.ID, err)
+		}
+	}
+}
+
+func (c *apiConfig) listModerationRules(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Rules []database.ModerationRule `json:"rules"`
+	}
+	rules, err := c.queries.ListModerationRules(r.Context())
+	if err != nil {
+		http.Error(w, "Could not retrieve rules", http.StatusInternalServerError)
+		log.Printf("Error listing moderation rules: %s", err)
+		return
+	}
+	res := responseStruct{Rules: rules}
+	if res.Rules == nil {
+		res.Rules = []database.ModerationRule{}
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&res)
+}
+
+func (c *apiConfig) createModerationRule(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Kind    string `json:"kind"`
+		Pattern string `json:"pattern"`
+		Action  string `json:"action"`
+		Reason  string `json:"reason"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	rule := moderation.Rule{
+		Kind:    moderation.Kind(req.Kind),
+		Pattern: strings.TrimSpace(req.Pattern),
+		Action:  moderation.Action(req.Action),
+		Reason:  strings.TrimSpace(req.Reason),
+	}
+	if err := rule.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Store words and domains the way they are matched, so the unique
+	// index catches duplicates written differently.
+	switch rule.Kind {
+	case moderation.KindWord:
+		rule.Pattern = moderation.Normalize(rule.Pattern)
+	case moderation.KindDomain:
+		rule.Pattern = moderation.NormalizeDomain(rule.Pattern)
+	}
+	row, err := c.queries.CreateModerationRule(r.Context(), database.CreateModerationRuleParams{
+		Kind:    string(rule.Kind),
+		Pattern: rule.Pattern,
+		Action:  string(rule.Action),
+		Reason:  rule.Reason,
+	})
+	if isUniqueViolation(err) {
+		http.Error(w, "A rule with this pattern already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error creating moderation rule: %s", err)
+		return
+	}
+	if !c.reloadModeration(w, r) {
+		return
+	}
+	log.Printf("User %s added moderation rule %s", callerID(r), row.ID)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&row)
+}
+
+func (c *apiConfig) deleteModerationRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	deleted, err := c.queries.DeleteModerationRule(r.Context(), ruleID)
+	if err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error deleting moderation rule %s: %s", ruleID, err)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Rule not found", http.StatusNotFound)
+		return
+	}
+	if !c.reloadModeration(w, r) {
+		return
+	}
+	log.Printf("User %s removed moderation rule %s", callerID(r), ruleID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listModerationRuleHits shows the most recent chirps a rule hit, newest
+// first.
+func (c *apiConfig) listModerationRuleHits(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Hits []database.ModerationHit `json:"hits"`
+	}
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hits, err := c.queries.ListModerationRuleHits(r.Context(), database.ListModerationRuleHitsParams{
+		RuleID: ruleID,
+		Limit:  limit,
+	})
+	if err != nil {
+		http.Error(w, "Could not retrieve hits", http.StatusInternalServerError)
+		log.Printf("Error listing hits of moderation rule %s: %s", ruleID, err)
+		return
+	}
+	res := responseStruct{Hits: hits}
+	if res.Hits == nil {
+		res.Hits = []database.ModerationHit{}
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&res)
+}
+
+// listHeldChirps is the review queue: chirps a hold rule kept back, oldest
+// first.
+func (c *apiConfig) listHeldChirps(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Chirps []chirpResponse `json:"chirps"`
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := c.queries.ListHeldChirps(r.Context(), limit)
+	if err != nil {
+		http.Error(w, "Could not retrieve held chirps", http.StatusInternalServerError)
+		log.Printf("Error listing held chirps: %s", err)
+		return
+	}
+	chirps, err := c.chirpResponses(r.Context(), data, uuid.NullUUID{})
+	if err != nil {
+		http.Error(w, "Could not retrieve held chirps", http.StatusInternalServerError)
+		log.Printf("Error loading held chirps: %s", err)
+		return
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&responseStruct{Chirps: chirps})
+}
+
+// setChirpModerationStatus lets an admin approve a held chirp, hide it, or
+// put it back into review.
+func (c *apiConfig) setChirpModerationStatus(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Status string `json:"status"`
+	}
+	defer r.Body.Close()
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	if _, ok := chirpStatusOrder[req.Status]; !ok {
+		http.Error(w, "Status must be visible, pending or hidden", http.StatusBadRequest)
+		return
+	}
+	chirp, err := c.queries.SetChirpModerationStatus(r.Context(), database.SetChirpModerationStatusParams{
+		ID:               chirpID,
+		ModerationStatus: req.Status,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Can not find chirp", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error setting moderation status of chirp %s: %s", chirpID, err)
+		return
+	}
+	log.Printf("User %s set chirp %s to %s", callerID(r), chirpID, req.Status)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&chirp)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DEFAULTPAGESIZE int32 = 20
+	MAXPAGESIZE     int32 = 100
+)
+
+// cursor is the keyset position of a row, ordered by (created_at, id).
+type cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// pageRequest holds the parsed limit, after, before and sort query parameters.
+type pageRequest struct {
+	Limit    int32
+	Cursor   *cursor
+	Backward bool // true when paging with "before" instead of "after"
+	Desc     bool
+}
+
+type page struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, errors.New("malformed cursor")
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return cursor{}, errors.New("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return cursor{}, errors.New("malformed cursor")
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return cursor{}, errors.New("malformed cursor")
+	}
+	return cursor{CreatedAt: createdAt, ID: parsedID}, nil
+}
+
+func parseLimit(q url.Values) (int32, error) {
+	l := q.Get("limit")
+	if l == "" {
+		return DEFAULTPAGESIZE, nil
+	}
+	limit, err := strconv.Atoi(l)
+	if err != nil || limit < 1 || limit > int(MAXPAGESIZE) {
+		return 0, fmt.Errorf("limit must be between 1 and %d", MAXPAGESIZE)
+	}
+	return int32(limit), nil
+}
+
+func parsePageRequest(q url.Values) (pageRequest, error) {
+	limit, err := parseLimit(q)
+	if err != nil {
+		return pageRequest{}, err
+	}
+	req := pageRequest{Limit: limit}
+	switch q.Get("sort") {
+	case "", "asc":
+	case "desc":
+		req.Desc = true
+	default:
+		return pageRequest{}, errors.New("sort must be asc or desc")
+	}
+	after, before := q.Get("after"), q.Get("before")
+	if after != "" && before != "" {
+		return pageRequest{}, errors.New("after and before can not be combined")
+	}
+	if after != "" || before != "" {
+		c, err := decodeCursor(after + before)
+		if err != nil {
+			return pageRequest{}, err
+		}
+		req.Cursor = &c
+		req.Backward = before != ""
+	}
+	return req, nil
+}
+
+// queryDesc reports whether the keyset query has to walk the index in
+// descending order. Paging backwards flips the requested sort order.
+func (p pageRequest) queryDesc() bool {
+	return p.Desc != p.Backward
+}
+
+// paginate trims the limit+1 rows returned by a keyset query down to one page,
+// restores the requested order and works out the cursors of neighbouring pages.
+func paginate[T any](items []T, req pageRequest, key func(T) cursor) ([]T, page) {
+	hasMore := len(items) > int(req.Limit)
+	if hasMore {
+		items = items[:req.Limit]
+	}
+	if req.Backward {
+		slices.Reverse(items)
+	}
+	var p page
+	if len(items) == 0 {
+		return items, p
+	}
+	if (!req.Backward && hasMore) || (req.Backward && req.Cursor != nil) {
+		p.NextCursor = encodeCursor(key(items[len(items)-1]))
+	}
+	if (req.Backward && hasMore) || (!req.Backward && req.Cursor != nil) {
+		p.PrevCursor = encodeCursor(key(items[0]))
+	}
+	return items, p
+}
+
+// setLinkHeader advertises the neighbouring pages as RFC 8288 links, keeping
+// every other query parameter of the current request.
+func setLinkHeader(w http.ResponseWriter, r *http.Request, p page) {
+	var links []string
+	link := func(param, value, rel string) {
+		q := r.URL.Query()
+		q.Del("after")
+		q.Del("before")
+		q.Set(param, value)
+		u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel))
+	}
+	if p.NextCursor != "" {
+		link("after", p.NextCursor, "next")
+	}
+	if p.PrevCursor != "" {
+		link("before", p.PrevCursor, "prev")
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
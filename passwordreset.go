@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_server/internal/auth"
+	"http_server/internal/database"
+	"http_server/internal/mail"
+	"http_server/internal/throttle"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	PASSWORDRESETTTL time.Duration = time.Hour
+	// PASSWORDRESETTIMEOUT bounds the lookup and delivery of one reset mail.
+	PASSWORDRESETTIMEOUT time.Duration = time.Second * 30
+	// PASSWORDRESETWORKERS mails are sent at a time; up to
+	// PASSWORDRESETQUEUE more wait, anything beyond that is dropped.
+	PASSWORDRESETWORKERS int = 4
+	PASSWORDRESETQUEUE   int = 64
+)
+
+// Reset mails are limited per address, so nobody's inbox can be flooded,
+// and per client IP. Every request counts, whether or not the address
+// exists.
+func newResetLimiters(store throttle.Store) (email, ip *throttle.Limiter) {
+	email = &throttle.Limiter{
+		Store:        store,
+		FreeAttempts: 3,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour * 24,
+	}
+	ip = &throttle.Limiter{
+		Store:        store,
+		FreeAttempts: 20,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	}
+	return email, ip
+}
+
+// startPasswordResetWorkers starts the goroutines that send the mails
+// queued by requestPasswordReset.
+func (c *apiConfig) startPasswordResetWorkers() {
+	c.passwordResets = make(chan string, PASSWORDRESETQUEUE)
+	for range PASSWORDRESETWORKERS {
+		go func() {
+			for email := range c.passwordResets {
+				c.sendPasswordReset(email)
+			}
+		}()
+	}
+}
+
+// passwordResetAllowed counts a reset request for email from r and reports
+// whether it is within the limits. Like the login throttle it fails open.
+func (c *apiConfig) passwordResetAllowed(r *http.Request, email string) bool {
+	now := time.Now()
+	allowed := true
+	for key, limiter := range map[string]*throttle.Limiter{
+		"reset:" + emailThrottleKey(email):    c.resetEmailLimiter,
+		"reset:" + ipThrottleKey(clientIP(r)): c.resetIPLimiter,
+	} {
+		wait, err := limiter.RetryAfter(r.Context(), key, now)
+		if err != nil {
+			log.Printf("Error checking password reset throttle: %s", err)
+		}
+		if wait > 0 {
+			allowed = false
+		}
+		if _, _, err := limiter.Fail(r.Context(), key, now); err != nil {
+			log.Printf("Error recording password reset request: %s", err)
+		}
+	}
+	return allowed
+}
+
+// requestPasswordReset mails a single use reset link to the address if it
+// belongs to an account. It always answers 202 without waiting for the
+// lookup or the mail, so neither the status nor the response time tells
+// which addresses are registered. Requests over the limits or beyond the
+// queue are dropped just as silently.
+func (c *apiConfig) requestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Email string `json:"email"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	if c.passwordResetAllowed(r, req.Email) {
+		select {
+		case c.passwordResets <- req.Email:
+		default:
+			log.Printf("Password reset queue is full, dropping request")
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendPasswordReset does the work of requestPasswordReset on one of the
+// workers, after the response has been written. Errors can only be logged.
+func (c *apiConfig) sendPasswordReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), PASSWORDRESETTIMEOUT)
+	defer cancel()
+	user, err := c.queries.GetUserFromEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error looking up password reset address: %s", err)
+		}
+		return
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("Error creating reset token: %s", err)
+		return
+	}
+	err = c.queries.InsertPasswordReset(ctx, database.InsertPasswordResetParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(PASSWORDRESETTTL),
+	})
+	if err != nil {
+		log.Printf("Error inserting password reset for %s: %s", user.ID, err)
+		return
+	}
+	link := c.appURL + "/app/reset-password.html?token=" + url.QueryEscape(token)
+	err = c.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Chirpy account.\n\n"+
+			"Use this link within %s to choose a new password:\n%s\n\n"+
+			"If this was not you, you can ignore this message.\n", PASSWORDRESETTTL, link),
+	})
+	if err != nil {
+		log.Printf("Error sending password reset mail to %s: %s", user.ID, err)
+	}
+}
+
+// confirmPasswordReset sets a new password with a token from
+// requestPasswordReset. Every session of the account is revoked with it.
+func (c *apiConfig) confirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	// The token is only consumed once the new password is acceptable, so a
+	// rejected password can be corrected without requesting a new mail.
+	tokenHash := auth.HashToken(req.Token)
+	pending, err := c.queries.GetPasswordReset(r.Context(), tokenHash)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	user, err := c.queries.GetUserByID(r.Context(), pending.UserID)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	if !c.checkPassword(w, req.Password, user.Email) {
+		return
+	}
+	hashedPassword, err := c.hasher.Hash(req.Password)
+	if err != nil {
+		http.Error(w, "Could not process password", http.StatusInternalServerError)
+		return
+	}
+	// A reset after a compromise must not leave any session behind, so the
+	// password only changes together with the revocation.
+	err = c.inTx(r.Context(), func(q *database.Queries) error {
+		reset, err := q.ConsumePasswordReset(r.Context(), tokenHash)
+		if err != nil {
+			return err
+		}
+		err = q.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:             reset.UserID,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.DeletePasswordResets(r.Context(), reset.UserID); err != nil {
+			return err
+		}
+		return q.RevokeAllUserTokens(r.Context(), reset.UserID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Interal database error", http.StatusInternalServerError)
+		log.Printf("Error resetting password for %s: %s", pending.UserID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
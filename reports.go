@@ -0,0 +1,303 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const MAXREPORTDETAILSLENGTH int = 500
+
+// reportReasons are the reason codes a chirp can be reported with.
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"sexual":         true,
+	"self_harm":      true,
+	"misinformation": true,
+	"other":          true,
+}
+
+// Ways a report can be resolved, as stored in reports.resolution.
+const (
+	REPORTDISMISSED       = "dismissed"
+	REPORTCHIRPDELETED    = "chirp_deleted"
+	REPORTAUTHORSUSPENDED = "author_suspended"
+)
+
+// errReportResolved means another admin resolved the report first.
+var errReportResolved = errors.New("report already resolved")
+
+// reportChirp files a report against someone else's chirp for the moderation
+// queue.
+func (c *apiConfig) reportChirp(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	defer r.Body.Close()
+	userID := callerID(r)
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		http.Error(w, "unable to parse chirpID", http.StatusNotFound)
+		return
+	}
+	chirp, err := c.queries.GetSingleChirp(r.Context(), chirpID)
+	if err != nil || !c.canSee(r.Context(), chirp, uuid.NullUUID{UUID: userID, Valid: true}) {
+		http.Error(w, "Can not find chirp", http.StatusNotFound)
+		return
+	}
+	if chirp.UserID == userID {
+		http.Error(w, "Can not report your own chirp", http.StatusBadRequest)
+		return
+	}
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	if !reportReasons[req.Reason] {
+		http.Error(w, "Unknown report reason", http.StatusBadRequest)
+		return
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if len(req.Details) > MAXREPORTDETAILSLENGTH {
+		http.Error(w, fmt.Sprintf("Details can be at most %d bytes", MAXREPORTDETAILSLENGTH), http.StatusBadRequest)
+		return
+	}
+	report, err := c.queries.CreateReport(r.Context(), database.CreateReportParams{
+		ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		AuthorID:   chirp.UserID,
+		ReporterID: userID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+	})
+	if isUniqueViolation(err) {
+		http.Error(w, "You already reported this chirp", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Interal database error", http.StatusInternalServerError)
+		log.Printf("Error reporting chirp %s: %s", chirp.ID, err)
+		return
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&report)
+}
+
+// listOpenReports is the moderation queue: unresolved reports, oldest
+// first.
+func (c *apiConfig) listOpenReports(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Reports []database.Report `json:"reports"`
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reports, err := c.queries.ListOpenReports(r.Context(), limit)
+	if err != nil {
+		http.Error(w, "Could not retrieve reports", http.StatusInternalServerError)
+		log.Printf("Error listing open reports: %s", err)
+		return
+	}
+	res := responseStruct{Reports: reports}
+	if res.Reports == nil {
+		res.Reports = []database.Report{}
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&res)
+}
+
+// getReport shows a report together with what a moderator needs to decide
+// on it: the chirp, the chirps it replies to, every report filed against it
+// and its author.
+func (c *apiConfig) getReport(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Report    database.Report   `json:"report"`
+		Chirp     *chirpResponse    `json:"chirp"`
+		Ancestors []chirpResponse   `json:"ancestors"`
+		Reports   []database.Report `json:"reports"`
+		Author    profile           `json:"author"`
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	report, err := c.queries.GetReport(r.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not retrieve report", http.StatusInternalServerError)
+		log.Printf("Error retrieving report %s: %s", reportID, err)
+		return
+	}
+	author, err := c.queries.GetUserByID(r.Context(), report.AuthorID)
+	if err != nil {
+		http.Error(w, "Could not retrieve report", http.StatusInternalServerError)
+		log.Printf("Error retrieving author of report %s: %s", reportID, err)
+		return
+	}
+	res := responseStruct{
+		Report:    report,
+		Ancestors: []chirpResponse{},
+		Reports:   []database.Report{report},
+		Author:    profileOf(author),
+	}
+
+	// chirp_id is cleared when the chirp is deleted.
+	if report.ChirpID.Valid {
+		chirp, err := c.queries.GetSingleChirp(r.Context(), report.ChirpID.UUID)
+		if err != nil {
+			http.Error(w, "Could not retrieve report", http.StatusInternalServerError)
+			log.Printf("Error retrieving chirp of report %s: %s", reportID, err)
+			return
+		}
+		ancestors, err := c.queries.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{ChirpID: chirp.ID})
+		if err != nil {
+			http.Error(w, "Could not retrieve report", http.StatusInternalServerError)
+			log.Printf("Error retrieving ancestors of chirp %s: %s", chirp.ID, err)
+			return
+		}
+		chirps, err := c.chirpResponses(r.Context(), append(ancestors, chirp), uuid.NullUUID{})
+		if err != nil {
+			http.Error(w, "Could not retrieve report", http.StatusInternalServerError)
+			log.Printf("Error loading chirps of report %s: %s", reportID, err)
+			return
+		}
+		res.Ancestors = chirps[:len(ancestors)]
+		res.Chirp = &chirps[len(ancestors)]
+		// Moderators see the real status, including shadow-hidden.
+		res.Chirp.Chirp = chirp
+		res.Reports, err = c.queries.ListChirpReports(r.Context(), report.ChirpID)
+		if err != nil {
+			http.Error(w, "Could not retrieve report", http.StatusInternalServerError)
+			log.Printf("Error listing reports of chirp %s: %s", chirp.ID, err)
+			return
+		}
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&res)
+}
+
+// resolveReport closes a report, and every other open report on the same
+// chirp, by dismissing it, deleting the chirp or suspending its author.
+func (c *apiConfig) resolveReport(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Action      string `json:"action"`
+		SuspendDays int    `json:"suspend_days"`
+		Reason      string `json:"reason"`
+	}
+	type responseStruct struct {
+		Resolved []database.Report `json:"resolved"`
+	}
+	defer r.Body.Close()
+	adminID := callerID(r)
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	var resolution string
+	switch req.Action {
+	case "dismiss":
+		resolution = REPORTDISMISSED
+	case "delete_chirp":
+		resolution = REPORTCHIRPDELETED
+	case "suspend_author":
+		resolution = REPORTAUTHORSUSPENDED
+	default:
+		http.Error(w, "Action must be dismiss, delete_chirp or suspend_author", http.StatusBadRequest)
+		return
+	}
+	duration, ok := suspensionDuration(req.SuspendDays)
+	if !ok {
+		http.Error(w, fmt.Sprintf("suspend_days must be between 0 and %d", MAXSUSPENSIONDAYS), http.StatusBadRequest)
+		return
+	}
+	report, err := c.queries.GetReport(r.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not retrieve report", http.StatusInternalServerError)
+		log.Printf("Error retrieving report %s: %s", reportID, err)
+		return
+	}
+	if report.ResolvedAt.Valid {
+		http.Error(w, "Report is already resolved", http.StatusConflict)
+		return
+	}
+
+	until := time.Now().Add(duration)
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "Reported for " + report.Reason
+	}
+
+	// The reports are only marked resolved if the action goes through.
+	var resolved []database.Report
+	var blobs []string
+	err = c.inTx(r.Context(), func(q *database.Queries) error {
+		// Resolve first: deleting the chirp clears chirp_id, which is how
+		// the other reports on it are found.
+		var err error
+		resolved, err = q.ResolveReports(r.Context(), database.ResolveReportsParams{
+			ID:         reportID,
+			ResolvedBy: uuid.NullUUID{UUID: adminID, Valid: true},
+			Resolution: sql.NullString{String: resolution, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if len(resolved) == 0 {
+			return errReportResolved
+		}
+		switch resolution {
+		case REPORTCHIRPDELETED:
+			if report.ChirpID.Valid {
+				blobs, err = deleteChirpWithMedia(r.Context(), q, report.ChirpID.UUID)
+			}
+		case REPORTAUTHORSUSPENDED:
+			_, err = suspendAccount(r.Context(), q, report.AuthorID, until, reason)
+		}
+		return err
+	})
+	if errors.Is(err, errReportResolved) {
+		http.Error(w, "Report is already resolved", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error resolving report %s as %s: %s", reportID, resolution, err)
+		return
+	}
+	c.deleteBlobs(r.Context(), blobs...)
+	log.Printf("Admin %s resolved report %s as %s", adminID, reportID, resolution)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&responseStruct{Resolved: resolved})
+}
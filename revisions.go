@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func (c *apiConfig) editChirp(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Body string `json:"body"`
+	}
+	defer r.Body.Close()
+	userID := callerID(r)
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		http.Error(w, "unable to parse chirpID", http.StatusNotFound)
+		return
+	}
+	chirp, err := c.queries.GetSingleChirp(r.Context(), chirpID)
+	if err != nil {
+		http.Error(w, "Can not find chirp", http.StatusNotFound)
+		return
+	}
+	if chirp.UserID != userID {
+		http.Error(w, "Not authorized to edit this chirp", http.StatusForbidden)
+		return
+	}
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Error unmarshaling request data", http.StatusUnprocessableEntity)
+		return
+	}
+	if len(req.Body) > MAXCHIRPLENGTH {
+		http.Error(w, `{"error": "Chirp is too long"}`, http.StatusBadRequest)
+		return
+	}
+
+	verdict, ok := c.moderateChirp(w, r, req.Body)
+	if !ok {
+		return
+	}
+	status := chirpStatus(verdict.Action)
+	if chirpStatusOrder[chirp.ModerationStatus] > chirpStatusOrder[status] {
+		status = chirp.ModerationStatus
+	}
+	updated, err := c.queries.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		ID:               chirp.ID,
+		Body:             verdict.Text,
+		ModerationStatus: status,
+	})
+	if err != nil {
+		http.Error(w, "Interal database error", http.StatusInternalServerError)
+		log.Printf("Error updating chirp %s: %s", chirp.ID, err)
+		return
+	}
+	c.recordModerationHits(r.Context(), userID, uuid.NullUUID{UUID: chirp.ID, Valid: true}, verdict)
+	updated = shadowHide(updated)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&updated)
+}
+
+func (c *apiConfig) getChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Revisions []database.ChirpRevision `json:"revisions"`
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	chirp, err := c.queries.GetSingleChirp(r.Context(), chirpID)
+	if err != nil || !c.canSee(r.Context(), chirp, c.viewerID(r)) {
+		http.Error(w, "Can not find chirp", http.StatusNotFound)
+		return
+	}
+	revisions, err := c.queries.ListChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		http.Error(w, "Could not retrieve revisions", http.StatusInternalServerError)
+		log.Printf("Error listing revisions of chirp %s: %s", chirpID, err)
+		return
+	}
+	if revisions == nil {
+		revisions = []database.ChirpRevision{}
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&responseStruct{Revisions: revisions})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+func (c *apiConfig) searchChirps(w http.ResponseWriter, r *http.Request) {
+	type result struct {
+		database.Chirp
+		Author  profile `json:"author"`
+		Rank    float32 `json:"rank"`
+		Snippet string  `json:"snippet"`
+	}
+	type responseStruct struct {
+		Results []result `json:"results"`
+	}
+	query := buildTSQuery(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Missing search query", http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := database.SearchChirpsParams{Query: query, ViewerID: c.viewerID(r), RowLimit: limit}
+	if author := r.URL.Query().Get("author_id"); author != "" {
+		userID, err := uuid.Parse(author)
+		if err != nil {
+			http.Error(w, "Invalid author_id", http.StatusBadRequest)
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+
+	rows, err := c.queries.SearchChirps(r.Context(), params)
+	if err != nil {
+		http.Error(w, "Could not search chirps", http.StatusInternalServerError)
+		log.Printf("Error searching chirps for %q: %s", query, err)
+		return
+	}
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, row.Chirp)
+	}
+	authors, err := c.authorProfiles(r.Context(), chirps)
+	if err != nil {
+		http.Error(w, "Could not search chirps", http.StatusInternalServerError)
+		log.Printf("Error loading authors of search results: %s", err)
+		return
+	}
+	res := responseStruct{Results: make([]result, 0, len(rows))}
+	for _, row := range rows {
+		res.Results = append(res.Results, result{
+			Chirp:   shadowHide(row.Chirp),
+			Author:  authors[row.Chirp.UserID],
+			Rank:    row.Rank,
+			Snippet: row.Snippet,
+		})
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(&res); err != nil {
+		log.Printf("Could not marshal search results: %s", err)
+	}
+}
+
+// buildTSQuery turns user input into a to_tsquery expression. Double quoted
+// parts become phrase queries, a trailing * makes a prefix query and all
+// terms have to match. Everything except letters and digits is dropped so
+// the user can not inject tsquery operators.
+func buildTSQuery(q string) string {
+	var terms []string
+	for i, part := range strings.Split(q, `"`) {
+		if i%2 == 1 {
+			if words := tsWords(part); len(words) > 0 {
+				terms = append(terms, tsPhrase(words))
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			words := tsWords(field)
+			if len(words) == 0 {
+				continue
+			}
+			if strings.HasSuffix(field, "*") {
+				words[len(words)-1] += ":*"
+			}
+			terms = append(terms, tsPhrase(words))
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+func tsWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func tsPhrase(words []string) string {
+	if len(words) == 1 {
+		return words[0]
+	}
+	return "(" + strings.Join(words, " <-> ") + ")"
+}
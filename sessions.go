@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"http_server/internal/database"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A session is a refresh token family: it starts at login and lives on
+// through every rotation. Its ID is the family ID, so the refresh token
+// itself is never exposed.
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (c *apiConfig) listSessions(w http.ResponseWriter, r *http.Request) {
+	type session struct {
+		ID         uuid.UUID `json:"id"`
+		CreatedAt  time.Time `json:"created_at"`
+		ExpiresAt  time.Time `json:"expires_at"`
+		LastUsedAt time.Time `json:"last_used_at"`
+		UserAgent  string    `json:"user_agent"`
+		IpAddress  string    `json:"ip_address"`
+	}
+	type responseStruct struct {
+		Sessions []session `json:"sessions"`
+	}
+	userID := callerID(r)
+	rows, err := c.queries.ListActiveSessions(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Could not retrieve sessions", http.StatusInternalServerError)
+		log.Printf("Error listing sessions for %s: %s", userID, err)
+		return
+	}
+	res := responseStruct{Sessions: make([]session, 0, len(rows))}
+	for _, row := range rows {
+		res.Sessions = append(res.Sessions, session{
+			ID:         row.FamilyID,
+			CreatedAt:  row.CreatedAt,
+			ExpiresAt:  row.ExpiresAt,
+			LastUsedAt: row.LastUsedAt,
+			UserAgent:  row.UserAgent,
+			IpAddress:  row.IpAddress,
+		})
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&res)
+}
+
+func (c *apiConfig) deleteSession(w http.ResponseWriter, r *http.Request) {
+	userID := callerID(r)
+	familyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	revoked, err := c.queries.RevokeUserTokenFamily(r.Context(), database.RevokeUserTokenFamilyParams{
+		UserID:   userID,
+		FamilyID: familyID,
+	})
+	if err != nil {
+		http.Error(w, "Could not revoke session", http.StatusInternalServerError)
+		log.Printf("Error revoking session %s: %s", familyID, err)
+		return
+	}
+	if revoked == 0 {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) logoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := callerID(r)
+	if err := c.queries.RevokeAllUserTokens(r.Context(), userID); err != nil {
+		http.Error(w, "Could not revoke sessions", http.StatusInternalServerError)
+		log.Printf("Error revoking all sessions for %s: %s", userID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
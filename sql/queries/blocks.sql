@@ -0,0 +1,24 @@
+-- name: BlockUser :exec
+WITH unfollow AS (
+    DELETE FROM follows
+    WHERE (follower_id = $1 AND followee_id = $2)
+    OR (follower_id = $2 AND followee_id = $1)
+)
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = $1 AND blocked_id = $2)
+    OR (blocker_id = $2 AND blocked_id = $1)
+);
@@ -1,11 +1,13 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, moderation_status)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
 RETURNING *;
 
@@ -13,14 +15,41 @@ RETURNING *;
 TRUNCATE TABLE chirps CASCADE;
 
 
--- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
-ORDER BY created_at ASC;
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (moderation_status = 'visible' OR user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+)
+AND (sqlc.narg('author_id')::uuid IS NOT NULL OR NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.narg('viewer_id')::uuid AND mutes.muted_id = chirps.user_id
+))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT @row_limit;
 
--- name: GetAllChirpsByAuthor :many
+-- name: ListChirpsDesc :many
 SELECT * FROM chirps
-where user_id = $1
-ORDER BY created_at ASC;
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (moderation_status = 'visible' OR user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+)
+AND (sqlc.narg('author_id')::uuid IS NOT NULL OR NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.narg('viewer_id')::uuid AND mutes.muted_id = chirps.user_id
+))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT @row_limit;
 
 -- name: GetSingleChirp :one
 SELECT * FROM chirps
@@ -28,4 +57,127 @@ where id = $1;
 
 -- name: DeleteSingleChirp :exec
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps),
+    ts_rank(to_tsvector('english', chirps.body), query)::real AS rank,
+    ts_headline('english',
+        replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+        query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')::text AS snippet
+FROM chirps, to_tsquery('english', @query) query
+WHERE to_tsvector('english', chirps.body) @@ query
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+AND (chirps.moderation_status = 'visible' OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT @row_limit;
+
+-- name: UpdateChirpBody :one
+WITH revision AS (
+    INSERT INTO chirp_revisions (chirp_id, body)
+    SELECT chirps.id, chirps.body FROM chirps
+    WHERE chirps.id = $1
+)
+UPDATE chirps
+SET body = $2,
+    moderation_status = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors (id, in_reply_to, depth) AS (
+    SELECT parent.id, parent.in_reply_to, 1
+    FROM chirps parent
+    JOIN chirps child ON child.in_reply_to = parent.id
+    WHERE child.id = @chirp_id
+    UNION ALL
+    SELECT chirps.id, chirps.in_reply_to, ancestors.depth + 1
+    FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT chirps.* FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+WHERE (chirps.moderation_status = 'visible' OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+)
+ORDER BY ancestors.depth DESC;
+
+-- name: ListChirpDescendantsAsc :many
+WITH RECURSIVE descendants (id) AS (
+    SELECT chirps.id FROM chirps
+    WHERE chirps.in_reply_to = @chirp_id::uuid
+    UNION ALL
+    SELECT chirps.id FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.id
+)
+SELECT chirps.* FROM chirps
+JOIN descendants ON chirps.id = descendants.id
+WHERE (chirps.moderation_status = 'visible' OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+)
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT @row_limit;
+
+-- name: ListChirpDescendantsDesc :many
+WITH RECURSIVE descendants (id) AS (
+    SELECT chirps.id FROM chirps
+    WHERE chirps.in_reply_to = @chirp_id::uuid
+    UNION ALL
+    SELECT chirps.id FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.id
+)
+SELECT chirps.* FROM chirps
+JOIN descendants ON chirps.id = descendants.id
+WHERE (chirps.moderation_status = 'visible' OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+)
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT @row_limit;
+
+-- name: GetChirpEngagement :many
+SELECT chirps.id,
+    (SELECT COUNT(*) FROM likes WHERE likes.chirp_id = chirps.id) AS like_count,
+    (SELECT COUNT(*) FROM rechirps WHERE rechirps.chirp_id = chirps.id) AS rechirp_count,
+    EXISTS (
+        SELECT 1 FROM likes
+        WHERE likes.chirp_id = chirps.id AND likes.user_id = sqlc.narg('viewer_id')::uuid
+    ) AS liked_by_me
+FROM chirps
+WHERE chirps.id = ANY(@chirp_ids::uuid[]);
+
+-- name: ListUserChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at, id;
+
+-- name: SetChirpModerationStatus :one
+UPDATE chirps
+SET moderation_status = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: ListHeldChirps :many
+SELECT * FROM chirps
+WHERE moderation_status = 'pending'
+ORDER BY created_at, id
+LIMIT $1;
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- name: InsertEmailVerification :exec
+INSERT INTO email_verifications (token_hash, created_at, user_id, email, expires_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4
+);
+
+-- name: ConsumeEmailVerification :one
+DELETE FROM email_verifications
+WHERE token_hash = $1
+AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteEmailVerifications :exec
+DELETE FROM email_verifications
+WHERE user_id = $1;
\ No newline at end of file
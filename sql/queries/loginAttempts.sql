@@ -0,0 +1,46 @@
+-- name: GetLoginAttempt :one
+SELECT * FROM login_attempts WHERE throttle_key = $1;
+
+-- name: IncrementLoginAttempt :one
+INSERT INTO login_attempts (throttle_key, failures, last_failure_at, locked_until)
+VALUES (
+    @throttle_key,
+    1,
+    @last_failure_at,
+    NULL
+)
+ON CONFLICT (throttle_key) DO UPDATE
+SET failures = CASE
+        WHEN login_attempts.last_failure_at < @expired_before
+            AND (login_attempts.locked_until IS NULL OR login_attempts.locked_until <= EXCLUDED.last_failure_at)
+        THEN 1
+        ELSE login_attempts.failures + 1
+    END,
+    last_failure_at = EXCLUDED.last_failure_at
+RETURNING *;
+
+-- name: LockLoginAttempt :execrows
+UPDATE login_attempts
+SET locked_until = @locked_until
+WHERE throttle_key = @throttle_key
+AND (locked_until IS NULL OR locked_until <= @now);
+
+-- name: DeleteLoginAttempt :exec
+DELETE FROM login_attempts WHERE throttle_key = $1;
+
+-- name: InsertLockoutEvent :exec
+INSERT INTO lockout_events (id, created_at, throttle_key, user_id, ip_address, failures, locked_until)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: ListLockoutEvents :many
+SELECT * FROM lockout_events
+ORDER BY created_at DESC, id DESC
+LIMIT $1;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- name: InsertLoginChallenge :one
+INSERT INTO login_challenges (token, created_at, user_id, expires_at, attempts)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    0
+)
+RETURNING *;
+
+-- name: CountLoginChallengeAttempt :one
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE token = $1
+RETURNING *;
+
+-- name: DeleteLoginChallenge :exec
+DELETE FROM login_challenges
+WHERE token = $1;
\ No newline at end of file
@@ -0,0 +1,68 @@
+-- name: InsertMedia :one
+INSERT INTO media (id, created_at, user_id, chirp_id, content_type, width, height, blob_key, thumbnail_key, kind)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    NULL,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: CountUnattachedMedia :one
+SELECT COUNT(*) FROM media
+WHERE id = ANY(sqlc.arg('media_ids')::uuid[])
+AND user_id = sqlc.arg('user_id')
+AND chirp_id IS NULL
+AND kind = 'chirp';
+
+-- name: AttachMedia :execrows
+UPDATE media
+SET chirp_id = sqlc.arg('chirp_id')
+WHERE id = ANY(sqlc.arg('media_ids')::uuid[])
+AND user_id = sqlc.arg('user_id')
+AND chirp_id IS NULL
+AND kind = 'chirp';
+
+-- name: ListChirpMedia :many
+SELECT * FROM media
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY created_at, id;
+
+-- name: DeletePurgeableMedia :many
+DELETE FROM media
+WHERE user_id IN (
+    SELECT id FROM users
+    WHERE deletion_requested_at < $1
+    FOR UPDATE
+)
+RETURNING blob_key, thumbnail_key;
+-- name: DeleteChirpMedia :many
+DELETE FROM media
+WHERE chirp_id = $1
+RETURNING blob_key, thumbnail_key;
+
+-- name: DeleteAvatarMedia :many
+DELETE FROM media
+USING users
+WHERE users.id = $1
+AND media.user_id = users.id
+AND media.kind = 'avatar'
+AND users.avatar_url LIKE '%/media/' || media.blob_key
+RETURNING media.blob_key, media.thumbnail_key;
+
+-- name: DeleteStaleMedia :many
+DELETE FROM media
+WHERE chirp_id IS NULL
+AND created_at < $1
+AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = media.user_id
+    AND users.avatar_url LIKE '%/media/' || media.blob_key
+)
+RETURNING blob_key, thumbnail_key;
@@ -0,0 +1,36 @@
+-- name: ListModerationRules :many
+SELECT * FROM moderation_rules
+ORDER BY created_at, id;
+
+-- name: CreateModerationRule :one
+INSERT INTO moderation_rules (id, created_at, kind, pattern, action, reason)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: DeleteModerationRule :execrows
+DELETE FROM moderation_rules WHERE id = $1;
+
+-- name: InsertModerationHit :exec
+INSERT INTO moderation_hits (id, created_at, rule_id, user_id, chirp_id, action, matched)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: ListModerationRuleHits :many
+SELECT * FROM moderation_hits
+WHERE rule_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
\ No newline at end of file
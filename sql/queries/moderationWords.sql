@@ -0,0 +1,14 @@
+-- name: ListModerationWords :many
+SELECT * FROM moderation_words
+ORDER BY word;
+
+-- name: AddModerationWord :exec
+INSERT INTO moderation_words (word, created_at)
+VALUES (
+    $1,
+    NOW()
+)
+ON CONFLICT (word) DO NOTHING;
+
+-- name: DeleteModerationWord :execrows
+DELETE FROM moderation_words WHERE word = $1;
\ No newline at end of file
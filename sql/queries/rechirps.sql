@@ -0,0 +1,12 @@
+-- name: Rechirp :exec
+INSERT INTO rechirps (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UndoRechirp :exec
+DELETE FROM rechirps
+WHERE user_id = $1 AND chirp_id = $2;
\ No newline at end of file
@@ -1,12 +1,15 @@
 -- name: InsertRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, user_agent, ip_address)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
     $3,
-    NULL
+    NULL,
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
@@ -20,5 +23,50 @@ SET revoked_at = NOW(),
     updated_at = NOW()
 WHERE token = $1;
 
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET replaced_by = $2,
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE token = $1
+AND replaced_by IS NULL
+AND revoked_at IS NULL;
+
+-- name: RevokeTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+AND revoked_at IS NULL;
+
+-- name: ListActiveSessions :many
+SELECT active.family_id,
+    (SELECT MIN(family.created_at) FROM refresh_tokens family
+    WHERE family.family_id = active.family_id)::timestamp AS created_at,
+    active.expires_at,
+    active.created_at AS last_used_at,
+    active.user_agent,
+    active.ip_address
+FROM refresh_tokens active
+WHERE active.user_id = $1
+AND active.revoked_at IS NULL
+AND active.expires_at > NOW()
+ORDER BY last_used_at DESC;
+
+-- name: RevokeUserTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+AND family_id = $2
+AND revoked_at IS NULL;
+
+-- name: RevokeAllUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL;
+
 -- name: DropAllTokens :exec
-TRUNCATE TABLE refresh_tokens CASCADE;
\ No newline at end of file
+TRUNCATE TABLE refresh_tokens CASCADE;
@@ -0,0 +1,35 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, chirp_id, author_id, reporter_id, reason, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports WHERE id = $1;
+
+-- name: ListOpenReports :many
+SELECT * FROM reports
+WHERE resolved_at IS NULL
+ORDER BY created_at, id
+LIMIT $1;
+
+-- name: ListChirpReports :many
+SELECT * FROM reports
+WHERE chirp_id = $1
+ORDER BY created_at, id;
+
+-- name: ResolveReports :many
+UPDATE reports
+SET resolved_at = NOW(),
+    resolved_by = $2,
+    resolution = $3
+WHERE resolved_at IS NULL
+AND (id = $1 OR chirp_id = (SELECT r.chirp_id FROM reports r WHERE r.id = $1))
+RETURNING *;
\ No newline at end of file
@@ -1,28 +1,155 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
 -- name: GetUserFromEmail :one
 SELECT * FROM users WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users WHERE lower(handle) = lower(sqlc.arg('handle'));
+
+-- name: ListUserProfiles :many
+SELECT id, handle, display_name, bio, avatar_url, created_at FROM users
+WHERE id = ANY(sqlc.arg('user_ids')::uuid[]);
+
 -- name: DropAllUsers :exec
 TRUNCATE TABLE users CASCADE;
 
--- name: UpdateUserEmailPassword :one
+-- name: UpdateUserPassword :exec
 UPDATE users
-SET email = $2,
-    hashed_password = $3
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RehashUserPassword :exec
+UPDATE users
+SET hashed_password = $2
 WHERE id = $1
-RETURNING *;
+AND hashed_password = sqlc.arg('old_hash');
 
 -- name: UpgradeUser :exec
 UPDATE users
 SET is_chirpy_red = TRUE
+WHERE id = $1;
+
+-- name: SetUserRoles :one
+UPDATE users
+SET roles = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2,
+    totp_enabled_at = NULL,
+    totp_last_step = 0,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableTOTP :exec
+UPDATE users
+SET totp_enabled_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_step = 0,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1
+AND totp_last_step < $2;
+
+-- name: SetPendingEmail :one
+UPDATE users
+SET pending_email = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ConfirmUserEmail :one
+UPDATE users
+SET email = $2,
+    pending_email = CASE WHEN pending_email = $2 THEN NULL ELSE pending_email END,
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+AND (email = $2 OR pending_email = $2)
+RETURNING *;
+
+-- name: UpdateUserFields :one
+UPDATE users
+SET pending_email = COALESCE(sqlc.narg('pending_email'), pending_email),
+    hashed_password = COALESCE(sqlc.narg('hashed_password'), hashed_password),
+    handle = COALESCE(sqlc.narg('handle'), handle),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_url = COALESCE(sqlc.narg('avatar_url'), avatar_url),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: RequestUserDeletion :one
+UPDATE users
+SET deletion_requested_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET deletion_requested_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: PurgeDeletedUsers :many
+DELETE FROM users
+WHERE deletion_requested_at < $1
+RETURNING id;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_until = $2,
+    suspension_reason = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: BanUser :one
+UPDATE users
+SET banned_at = NOW(),
+    suspension_reason = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: LiftUserSuspension :one
+UPDATE users
+SET suspended_until = NULL,
+    banned_at = NULL,
+    suspension_reason = '',
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUserStanding :one
+SELECT banned_at, suspended_until, suspension_reason FROM users
 WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE chirp_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE NOT NULL,
+    body TEXT NOT NULL
+);
+CREATE INDEX chirp_revisions_chirp_id_created_at_idx ON chirp_revisions (chirp_id, created_at);
+
+
+-- +goose Down
+DROP TABLE chirp_revisions;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE likes (
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, chirp_id)
+);
+CREATE INDEX likes_chirp_id_idx ON likes (chirp_id);
+
+
+-- +goose Down
+DROP TABLE likes;
\ No newline at end of file
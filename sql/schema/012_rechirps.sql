@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE rechirps (
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, chirp_id)
+);
+CREATE INDEX rechirps_chirp_id_idx ON rechirps (chirp_id);
+
+
+-- +goose Down
+DROP TABLE rechirps;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{user}';
+
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN roles;
\ No newline at end of file
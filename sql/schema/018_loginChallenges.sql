@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE login_challenges (
+    token TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0
+);
+
+
+-- +goose Down
+DROP TABLE login_challenges;
\ No newline at end of file
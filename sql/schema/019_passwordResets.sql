@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE password_resets (
+    token_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+CREATE INDEX password_resets_user_id_idx ON password_resets (user_id);
+
+
+-- +goose Down
+DROP TABLE password_resets;
\ No newline at end of file
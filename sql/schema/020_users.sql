@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN pending_email TEXT;
+
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN pending_email;
+ALTER TABLE users DROP COLUMN email_verified_at;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE email_verifications (
+    token_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    email TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+CREATE INDEX email_verifications_user_id_idx ON email_verifications (user_id);
+
+
+-- +goose Down
+DROP TABLE email_verifications;
\ No newline at end of file
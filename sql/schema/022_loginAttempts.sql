@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE login_attempts (
+    throttle_key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
+
+CREATE TABLE lockout_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    throttle_key TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    ip_address TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMP NOT NULL
+);
+CREATE INDEX lockout_events_created_at_idx ON lockout_events (created_at DESC, id DESC);
+
+
+-- +goose Down
+DROP TABLE lockout_events;
+DROP TABLE login_attempts;
\ No newline at end of file
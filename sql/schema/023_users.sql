@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN handle TEXT;
+UPDATE users SET handle = 'user_' || substr(md5(id::text), 1, 10);
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL;
+CREATE UNIQUE INDEX users_handle_idx ON users (lower(handle));
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';
+
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN avatar_url;
+ALTER TABLE users DROP COLUMN bio;
+ALTER TABLE users DROP COLUMN display_name;
+DROP INDEX users_handle_idx;
+ALTER TABLE users DROP COLUMN handle;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE moderation_words (
+    word TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL
+);
+
+
+-- +goose Down
+DROP TABLE moderation_words;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN moderation_status TEXT NOT NULL DEFAULT 'visible'
+CHECK (moderation_status IN ('visible', 'pending', 'hidden'));
+CREATE INDEX chirps_pending_idx ON chirps (created_at, id)
+WHERE moderation_status = 'pending';
+
+
+-- +goose Down
+DROP INDEX chirps_pending_idx;
+ALTER TABLE chirps DROP COLUMN moderation_status;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE moderation_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('word', 'regex', 'domain')),
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('mask', 'hold', 'hide', 'reject')),
+    reason TEXT NOT NULL DEFAULT '',
+    UNIQUE (kind, pattern)
+);
+CREATE TABLE moderation_hits (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    rule_id UUID REFERENCES moderation_rules(id) ON DELETE CASCADE NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    action TEXT NOT NULL,
+    matched TEXT NOT NULL
+);
+CREATE INDEX moderation_hits_rule_id_idx ON moderation_hits (rule_id, created_at, id);
+
+
+-- +goose Down
+DROP TABLE moderation_hits;
+DROP TABLE moderation_rules;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    author_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    reporter_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    resolved_at TIMESTAMP,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolution TEXT
+);
+CREATE UNIQUE INDEX reports_open_chirp_reporter_idx ON reports (chirp_id, reporter_id)
+WHERE resolved_at IS NULL;
+CREATE INDEX reports_open_idx ON reports (created_at, id)
+WHERE resolved_at IS NULL;
+
+
+-- +goose Down
+DROP TABLE reports;
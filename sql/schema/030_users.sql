@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN banned_at TIMESTAMP;
+
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN banned_at;
+ALTER TABLE users DROP COLUMN suspension_reason;
+ALTER TABLE users DROP COLUMN suspended_until;
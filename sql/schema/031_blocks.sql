@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE blocks (
+    blocker_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    blocked_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+
+CREATE TABLE mutes (
+    muter_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    muted_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (muter_id, muted_id),
+    CHECK (muter_id <> muted_id)
+);
+
+
+-- +goose Down
+DROP TABLE mutes;
+DROP TABLE blocks;
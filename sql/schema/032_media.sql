@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE media ADD COLUMN kind TEXT NOT NULL DEFAULT 'chirp' CHECK (kind IN ('chirp', 'avatar'));
+UPDATE media SET kind = 'avatar'
+FROM users
+WHERE users.id = media.user_id
+AND users.avatar_url LIKE '%/media/' || media.blob_key;
+
+
+-- +goose Down
+ALTER TABLE media DROP COLUMN kind;
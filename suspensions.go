@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DEFAULTSUSPENSION is how long an account is suspended when the moderator
+// does not say otherwise.
+const DEFAULTSUSPENSION time.Duration = time.Hour * 24 * 7
+
+// MAXSUSPENSIONDAYS is the longest suspension an admin can hand out; longer
+// than that is a ban. It also keeps days well clear of overflowing a
+// time.Duration, which would put the end of the suspension in the past.
+const MAXSUSPENSIONDAYS int = 3650
+
+// suspensionDuration turns a requested number of days into a duration, or
+// returns false if it is out of range. 0 means DEFAULTSUSPENSION.
+func suspensionDuration(days int) (time.Duration, bool) {
+	if days < 0 || days > MAXSUSPENSIONDAYS {
+		return 0, false
+	}
+	if days == 0 {
+		return DEFAULTSUSPENSION, true
+	}
+	return time.Duration(days) * time.Hour * 24, true
+}
+
+// standingOf picks the fields of user that decide whether they may use the
+// API, as loaded by GetUserStanding.
+func standingOf(user database.User) database.GetUserStandingRow {
+	return database.GetUserStandingRow{
+		BannedAt:         user.BannedAt,
+		SuspendedUntil:   user.SuspendedUntil,
+		SuspensionReason: user.SuspensionReason,
+	}
+}
+
+// suspended reports whether a user is banned or currently suspended.
+// Suspensions end by themselves, bans only when an admin lifts them.
+func suspended(s database.GetUserStandingRow) bool {
+	return s.BannedAt.Valid || (s.SuspendedUntil.Valid && time.Now().Before(s.SuspendedUntil.Time))
+}
+
+// accountSuspended tells a banned or suspended user why, and for how long.
+func accountSuspended(w http.ResponseWriter, s database.GetUserStandingRow) {
+	type responseStruct struct {
+		Error          string     `json:"error"`
+		Reason         string     `json:"reason,omitempty"`
+		SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	}
+	res := responseStruct{Error: "Account is banned", Reason: s.SuspensionReason}
+	if !s.BannedAt.Valid {
+		res.Error = "Account is suspended"
+		res.SuspendedUntil = &s.SuspendedUntil.Time
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(&res)
+}
+
+// checkStanding looks up whether the user behind an access token may still
+// use the API. Access tokens are not revoked, so this runs on every
+// authenticated request. It writes the error response and returns false if
+// not.
+func (c *apiConfig) checkStanding(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	standing, err := c.queries.GetUserStanding(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Token not valid", http.StatusUnauthorized)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Could not retrieve user", http.StatusInternalServerError)
+		log.Printf("Error retrieving standing of %s: %s", userID, err)
+		return false
+	}
+	if suspended(standing) {
+		accountSuspended(w, standing)
+		return false
+	}
+	return true
+}
+
+// suspendAccount locks a user out until the given time and ends every session
+// they have.
+func suspendAccount(ctx context.Context, q *database.Queries, userID uuid.UUID, until time.Time, reason string) (database.User, error) {
+	user, err := q.SuspendUser(ctx, database.SuspendUserParams{
+		ID:               userID,
+		SuspendedUntil:   sql.NullTime{Time: until, Valid: true},
+		SuspensionReason: reason,
+	})
+	if err != nil {
+		return user, err
+	}
+	return user, q.RevokeAllUserTokens(ctx, userID)
+}
+
+// banAccount locks a user out until an admin lifts the ban and ends every
+// session they have.
+func banAccount(ctx context.Context, q *database.Queries, userID uuid.UUID, reason string) (database.User, error) {
+	user, err := q.BanUser(ctx, database.BanUserParams{
+		ID:               userID,
+		SuspensionReason: reason,
+	})
+	if err != nil {
+		return user, err
+	}
+	return user, q.RevokeAllUserTokens(ctx, userID)
+}
+
+// setUserSuspension is shared by the admin endpoints that suspend, ban and
+// reinstate users. update runs in a transaction.
+func (c *apiConfig) setUserSuspension(w http.ResponseWriter, r *http.Request, update func(ctx context.Context, q *database.Queries, userID uuid.UUID) (database.User, error)) {
+	adminID := callerID(r)
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	if userID == adminID {
+		http.Error(w, "Can not change your own suspension", http.StatusBadRequest)
+		return
+	}
+	var user database.User
+	err = c.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		user, err = update(r.Context(), q, userID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error changing suspension of %s: %s", userID, err)
+		return
+	}
+	log.Printf("Admin %s changed suspension of user %s", adminID, userID)
+	user.HashedPassword = ""
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&user)
+}
+
+func (c *apiConfig) suspendUser(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Days   int    `json:"days"`
+		Reason string `json:"reason"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	duration, ok := suspensionDuration(req.Days)
+	if !ok {
+		http.Error(w, fmt.Sprintf("days must be between 0 and %d", MAXSUSPENSIONDAYS), http.StatusBadRequest)
+		return
+	}
+	c.setUserSuspension(w, r, func(ctx context.Context, q *database.Queries, userID uuid.UUID) (database.User, error) {
+		return suspendAccount(ctx, q, userID, time.Now().Add(duration), strings.TrimSpace(req.Reason))
+	})
+}
+
+func (c *apiConfig) banUser(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Reason string `json:"reason"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	c.setUserSuspension(w, r, func(ctx context.Context, q *database.Queries, userID uuid.UUID) (database.User, error) {
+		return banAccount(ctx, q, userID, strings.TrimSpace(req.Reason))
+	})
+}
+
+// liftUserSuspension ends a suspension or ban early. Sessions revoked by it
+// stay revoked; the user logs in again.
+func (c *apiConfig) liftUserSuspension(w http.ResponseWriter, r *http.Request) {
+	c.setUserSuspension(w, r, func(ctx context.Context, q *database.Queries, userID uuid.UUID) (database.User, error) {
+		return q.LiftUserSuspension(ctx, userID)
+	})
+}
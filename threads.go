@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// getChirpThread returns the chain of chirps the requested chirp replies to,
+// oldest first, together with one page of every reply below it.
+func (c *apiConfig) getChirpThread(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Ancestors []chirpResponse `json:"ancestors"`
+		Chirp     chirpResponse   `json:"chirp"`
+		Replies   []chirpResponse `json:"replies"`
+		page
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		http.Error(w, "Invalid UUID", http.StatusUnprocessableEntity)
+		return
+	}
+	pageReq, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	viewer := c.viewerID(r)
+	chirp, err := c.queries.GetSingleChirp(r.Context(), chirpID)
+	if err != nil || !c.canSee(r.Context(), chirp, viewer) {
+		http.Error(w, "Can not find chirp", http.StatusNotFound)
+		return
+	}
+	ancestors, err := c.queries.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+		ChirpID:  chirpID,
+		ViewerID: viewer,
+	})
+	if err != nil {
+		http.Error(w, "Could not retrieve thread", http.StatusInternalServerError)
+		log.Printf("Error retrieving ancestors of chirp %s: %s", chirpID, err)
+		return
+	}
+
+	params := database.ListChirpDescendantsAscParams{ChirpID: chirpID, ViewerID: viewer, RowLimit: pageReq.Limit + 1}
+	if pageReq.Cursor != nil {
+		params.CursorCreatedAt = sql.NullTime{Time: pageReq.Cursor.CreatedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: pageReq.Cursor.ID, Valid: true}
+	}
+	var replies []database.Chirp
+	if pageReq.queryDesc() {
+		replies, err = c.queries.ListChirpDescendantsDesc(r.Context(), database.ListChirpDescendantsDescParams(params))
+	} else {
+		replies, err = c.queries.ListChirpDescendantsAsc(r.Context(), params)
+	}
+	if err != nil {
+		http.Error(w, "Could not retrieve thread", http.StatusInternalServerError)
+		log.Printf("Error retrieving replies to chirp %s: %s", chirpID, err)
+		return
+	}
+	replies, p := paginate(replies, pageReq, func(chirp database.Chirp) cursor {
+		return cursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+	})
+
+	// Load everything in one go, then split it up again.
+	all := append(append(ancestors, chirp), replies...)
+	chirps, err := c.chirpResponses(r.Context(), all, viewer)
+	if err != nil {
+		http.Error(w, "Could not retrieve thread", http.StatusInternalServerError)
+		log.Printf("Error loading thread of chirp %s: %s", chirpID, err)
+		return
+	}
+	res := responseStruct{
+		Ancestors: chirps[:len(ancestors)],
+		Chirp:     chirps[len(ancestors)],
+		Replies:   chirps[len(ancestors)+1:],
+		page:      p,
+	}
+	setLinkHeader(w, r, p)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(&res); err != nil {
+		log.Printf("Could not marshal thread: %s", err)
+	}
+}
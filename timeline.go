@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// getTimeline returns the chirps of every account the caller follows, newest
+// first. sort=asc is refused rather than ignored; ListTimelineAsc is still
+// needed to page backwards with "before".
+func (c *apiConfig) getTimeline(w http.ResponseWriter, r *http.Request) {
+	type responseStruct struct {
+		Chirps []chirpResponse `json:"chirps"`
+		page
+	}
+	userID := callerID(r)
+	if r.URL.Query().Get("sort") == "asc" {
+		http.Error(w, "The timeline is always sorted newest first", http.StatusBadRequest)
+		return
+	}
+	pageReq, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageReq.Desc = true
+
+	params := database.ListTimelineAscParams{UserID: userID, RowLimit: pageReq.Limit + 1}
+	if pageReq.Cursor != nil {
+		params.CursorCreatedAt = sql.NullTime{Time: pageReq.Cursor.CreatedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: pageReq.Cursor.ID, Valid: true}
+	}
+	var data []database.Chirp
+	if pageReq.queryDesc() {
+		data, err = c.queries.ListTimelineDesc(r.Context(), database.ListTimelineDescParams(params))
+	} else {
+		data, err = c.queries.ListTimelineAsc(r.Context(), params)
+	}
+	if err != nil {
+		http.Error(w, "Could not retrieve timeline", http.StatusInternalServerError)
+		log.Printf("Error listing timeline for %s: %s", userID, err)
+		return
+	}
+	data, p := paginate(data, pageReq, func(chirp database.Chirp) cursor {
+		return cursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+	})
+	chirps, err := c.chirpResponses(r.Context(), data, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		http.Error(w, "Could not retrieve timeline", http.StatusInternalServerError)
+		log.Printf("Error loading timeline chirps for %s: %s", userID, err)
+		return
+	}
+
+	setLinkHeader(w, r, p)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(&responseStruct{Chirps: chirps, page: p}); err != nil {
+		log.Printf("Could not marshal timeline: %s", err)
+	}
+}
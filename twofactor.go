@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"http_server/internal/auth"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TOTPISSUER           string        = "Chirpy"
+	RECOVERYCODECOUNT    int           = 10
+	LOGINCHALLENGETTL    time.Duration = time.Minute * 5
+	MAXCHALLENGEATTEMPTS int32         = 5
+)
+
+// enrollTOTP creates a new TOTP secret for the caller. It only protects the
+// account once a code for it has been confirmed through verifyTOTP. Like
+// other sensitive changes it needs the current password, so a stolen access
+// token can not put the attacker's authenticator on the account.
+func (c *apiConfig) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		CurrentPassword string `json:"current_password"`
+	}
+	type responseStruct struct {
+		Secret        string   `json:"secret"`
+		OtpauthURI    string   `json:"otpauth_uri"`
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	user, err := c.queries.GetUserByID(r.Context(), callerID(r))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !c.checkCurrentPassword(w, r, user, req.CurrentPassword) {
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		http.Error(w, "Could not create secret", http.StatusInternalServerError)
+		return
+	}
+	codes, err := auth.GenerateRecoveryCodes(RECOVERYCODECOUNT)
+	if err != nil {
+		http.Error(w, "Could not create recovery codes", http.StatusInternalServerError)
+		return
+	}
+	// The secret and its recovery codes are stored together, so a failure
+	// can not leave a secret that verifyTOTP would enable without codes.
+	err = c.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.SetTOTPSecret(r.Context(), database.SetTOTPSecretParams{
+			ID:         user.ID,
+			TotpSecret: sql.NullString{String: secret, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(r.Context(), q, user, codes)
+	})
+	if err != nil {
+		http.Error(w, "Interal database error", http.StatusInternalServerError)
+		log.Printf("Error storing TOTP secret for %s: %s", user.ID, err)
+		return
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&responseStruct{
+		Secret:        secret,
+		OtpauthURI:    auth.TOTPURI(secret, user.Email, TOTPISSUER),
+		RecoveryCodes: codes,
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *database.Queries, user database.User, codes []string) error {
+	if err := q.DeleteRecoveryCodes(ctx, user.ID); err != nil {
+		return err
+	}
+	for _, code := range codes {
+		err := q.InsertRecoveryCode(ctx, database.InsertRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyTOTP confirms a pending enrollment with a code from the
+// authenticator app and turns two-factor authentication on.
+func (c *apiConfig) verifyTOTP(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Code string `json:"code"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	user, err := c.queries.GetUserByID(r.Context(), callerID(r))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		http.Error(w, "No two-factor enrollment in progress", http.StatusBadRequest)
+		return
+	}
+	if !c.checkSecondFactor(r.Context(), user, req.Code, "") {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+	if err := c.queries.EnableTOTP(r.Context(), user.ID); err != nil {
+		http.Error(w, "Interal database error", http.StatusInternalServerError)
+		log.Printf("Error enabling TOTP for %s: %s", user.ID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	user, err := c.queries.GetUserByID(r.Context(), callerID(r))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+		return
+	}
+	if !c.checkSecondFactor(r.Context(), user, req.Code, req.RecoveryCode) {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+	if err := c.queries.DisableTOTP(r.Context(), user.ID); err != nil {
+		http.Error(w, "Interal database error", http.StatusInternalServerError)
+		log.Printf("Error disabling TOTP for %s: %s", user.ID, err)
+		return
+	}
+	if err := c.queries.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+		log.Printf("Error deleting recovery codes for %s: %s", user.ID, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkSecondFactor accepts either a TOTP code, which can only be used once
+// per time step, or an unused recovery code, which is burnt on success.
+func (c *apiConfig) checkSecondFactor(ctx context.Context, user database.User, code, recoveryCode string) bool {
+	if code != "" && user.TotpSecret.Valid {
+		step, ok := auth.ValidateTOTP(user.TotpSecret.String, code, time.Now())
+		if !ok {
+			return false
+		}
+		used, err := c.queries.UseTOTPStep(ctx, database.UseTOTPStepParams{ID: user.ID, TotpLastStep: step})
+		if err != nil {
+			log.Printf("Error recording TOTP step for %s: %s", user.ID, err)
+			return false
+		}
+		return used == 1
+	}
+	if recoveryCode != "" {
+		used, err := c.queries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashRecoveryCode(recoveryCode),
+		})
+		if err != nil {
+			log.Printf("Error using recovery code for %s: %s", user.ID, err)
+			return false
+		}
+		return used == 1
+	}
+	return false
+}
+
+// startLoginChallenge answers a correct password for an account with
+// two-factor authentication enabled. The client has to complete the login by
+// sending the challenge and a code to loginTwoFactor.
+func (c *apiConfig) startLoginChallenge(w http.ResponseWriter, r *http.Request, user database.User) {
+	type responseStruct struct {
+		TwoFactorRequired bool      `json:"two_factor_required"`
+		Challenge         string    `json:"challenge"`
+		ExpiresAt         time.Time `json:"expires_at"`
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		http.Error(w, "Error creating login challenge", http.StatusInternalServerError)
+		return
+	}
+	challenge, err := c.queries.InsertLoginChallenge(r.Context(), database.InsertLoginChallengeParams{
+		Token:     token,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(LOGINCHALLENGETTL),
+	})
+	if err != nil {
+		http.Error(w, "Error creating login challenge", http.StatusInternalServerError)
+		log.Printf("Error inserting login challenge for %s: %s", user.ID, err)
+		return
+	}
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(&responseStruct{
+		TwoFactorRequired: true,
+		Challenge:         challenge.Token,
+		ExpiresAt:         challenge.ExpiresAt,
+	})
+}
+
+func (c *apiConfig) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Challenge    string `json:"challenge"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	challenge, err := c.queries.CountLoginChallengeAttempt(r.Context(), req.Challenge)
+	if err != nil {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts > MAXCHALLENGEATTEMPTS {
+		c.queries.DeleteLoginChallenge(r.Context(), challenge.Token)
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	user, err := c.queries.GetUserByID(r.Context(), challenge.UserID)
+	if err != nil {
+		http.Error(w, "Could not retrieve user", http.StatusInternalServerError)
+		log.Printf("Error retrieving user %s: %s", challenge.UserID, err)
+		return
+	}
+	// Wrong codes count against the same per-user limit as wrong
+	// passwords, so opening new challenges does not buy more guesses.
+	if wait := c.loginRetryAfter(r, user.Email); wait > 0 {
+		tooManyRequests(w, wait)
+		return
+	}
+	if !c.checkSecondFactor(r.Context(), user, req.Code, req.RecoveryCode) {
+		c.loginFailed(r, user.Email, uuid.NullUUID{UUID: user.ID, Valid: true})
+		http.Error(w, "Authentication failed", http.StatusUnauthorized)
+		return
+	}
+	if s := standingOf(user); suspended(s) {
+		accountSuspended(w, s)
+		return
+	}
+	if err := c.queries.DeleteLoginChallenge(r.Context(), challenge.Token); err != nil {
+		log.Printf("Error deleting login challenge: %s", err)
+	}
+	c.startSession(w, r, user)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"http_server/internal/auth"
+	"http_server/internal/database"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	MAXDISPLAYNAMELENGTH int = 50
+	MAXBIOLENGTH         int = 160
+)
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+// profile is the public part of an account, shown on profile pages and as
+// the author of chirps.
+type profile struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarUrl   string    `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func profileOf(user database.User) profile {
+	return profile{
+		ID:          user.ID,
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarUrl:   user.AvatarUrl,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+// parseHandle accepts a handle with or without the leading @.
+func parseHandle(handle string) (string, bool) {
+	handle = strings.TrimPrefix(handle, "@")
+	return handle, handlePattern.MatchString(handle)
+}
+
+// generateHandle picks a placeholder handle for accounts created without one.
+func generateHandle() string {
+	var b [5]byte
+	rand.Read(b[:])
+	return "user_" + hex.EncodeToString(b[:])
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func validAvatarURL(s string) bool {
+	if s == "" {
+		return true
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// authorProfiles loads the profiles of everyone who wrote one of chirps.
+func (c *apiConfig) authorProfiles(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID]profile, error) {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.UserID)
+	}
+	rows, err := c.queries.ListUserProfiles(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[uuid.UUID]profile, len(rows))
+	for _, row := range rows {
+		profiles[row.ID] = profile(row)
+	}
+	return profiles, nil
+}
+
+func (c *apiConfig) getUserProfile(w http.ResponseWriter, r *http.Request) {
+	handle, ok := parseHandle(r.PathValue("handle"))
+	if !ok {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	user, err := c.queries.GetUserByHandle(r.Context(), handle)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	// Blocks hide both users from each other, profiles included.
+	if viewer := c.viewerID(r); viewer.Valid && viewer.UUID != user.ID {
+		blocked, err := c.blockedBetween(r.Context(), viewer.UUID, user.ID)
+		if err != nil {
+			http.Error(w, "Could not retrieve user", http.StatusInternalServerError)
+			log.Printf("Error checking blocks between %s and %s: %s", viewer.UUID, user.ID, err)
+			return
+		}
+		if blocked {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+	}
+	res := profileOf(user)
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&res)
+}
+
+// checkCurrentPassword guards changes of the login email or password: an
+// access token alone is not enough. Wrong guesses count against the login
+// throttle. It writes the error response and returns false on failure.
+func (c *apiConfig) checkCurrentPassword(w http.ResponseWriter, r *http.Request, user database.User, password string) bool {
+	if wait := c.loginRetryAfter(r, user.Email); wait > 0 {
+		tooManyRequests(w, wait)
+		return false
+	}
+	if err := auth.CheckPasswordHash(password, user.HashedPassword); err != nil {
+		c.loginFailed(r, user.Email, uuid.NullUUID{UUID: user.ID, Valid: true})
+		http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// patchUser changes only the fields present in the request. Changing the
+// email or the password needs the current password, so a stolen access
+// token is not enough to take over the account. Profile fields do not.
+func (c *apiConfig) patchUser(w http.ResponseWriter, r *http.Request) {
+	type requestStruct struct {
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+		Handle          *string `json:"handle"`
+		DisplayName     *string `json:"display_name"`
+		Bio             *string `json:"bio"`
+		AvatarUrl       *string `json:"avatar_url"`
+	}
+	defer r.Body.Close()
+	var req requestStruct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request", http.StatusUnprocessableEntity)
+		return
+	}
+	userID := callerID(r)
+	user, err := c.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if (req.Email != nil || req.Password != nil) && !c.checkCurrentPassword(w, r, user, req.CurrentPassword) {
+		return
+	}
+
+	params := database.UpdateUserFieldsParams{ID: userID}
+	email := user.Email
+	// A new address is only stored as pending until verifyEmail confirms it.
+	if req.Email != nil && *req.Email != user.Email {
+		if *req.Email == "" {
+			http.Error(w, "Email must not be empty", http.StatusBadRequest)
+			return
+		}
+		email = *req.Email
+		params.PendingEmail = sql.NullString{String: email, Valid: true}
+	}
+	if req.Password != nil {
+		if !c.checkPassword(w, *req.Password, email) {
+			return
+		}
+		hashedPassword, err := c.hasher.Hash(*req.Password)
+		if err != nil {
+			http.Error(w, "Could not process password", http.StatusInternalServerError)
+			return
+		}
+		params.HashedPassword = sql.NullString{String: hashedPassword, Valid: true}
+	}
+
+	if req.Handle != nil {
+		handle, ok := parseHandle(*req.Handle)
+		if !ok {
+			http.Error(w, "Handles are 3 to 30 letters, digits or underscores", http.StatusBadRequest)
+			return
+		}
+		params.Handle = sql.NullString{String: handle, Valid: true}
+	}
+	if req.DisplayName != nil {
+		if utf8.RuneCountInString(*req.DisplayName) > MAXDISPLAYNAMELENGTH {
+			http.Error(w, "Display name is too long", http.StatusBadRequest)
+			return
+		}
+		params.DisplayName = sql.NullString{String: strings.TrimSpace(*req.DisplayName), Valid: true}
+	}
+	if req.Bio != nil {
+		if utf8.RuneCountInString(*req.Bio) > MAXBIOLENGTH {
+			http.Error(w, "Bio is too long", http.StatusBadRequest)
+			return
+		}
+		params.Bio = sql.NullString{String: *req.Bio, Valid: true}
+	}
+	if req.AvatarUrl != nil {
+		if !validAvatarURL(*req.AvatarUrl) {
+			http.Error(w, "Avatar must be an http(s) URL", http.StatusBadRequest)
+			return
+		}
+		params.AvatarUrl = sql.NullString{String: *req.AvatarUrl, Valid: true}
+	}
+
+	dbUser, err := c.queries.UpdateUserFields(r.Context(), params)
+	if isUniqueViolation(err) {
+		http.Error(w, "Handle is already taken", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unable to update database", http.StatusInternalServerError)
+		log.Printf("Error updating user %s: %s", userID, err)
+		return
+	}
+	if params.PendingEmail.Valid {
+		if err := c.sendEmailVerification(r.Context(), dbUser, email); err != nil {
+			log.Printf("Error sending verification mail to %s: %s", userID, err)
+		}
+	}
+	dbUser.HashedPassword = ""
+	w.Header().Set(CONTENTTYPE, APPTYPE)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&dbUser)
+}